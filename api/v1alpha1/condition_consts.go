@@ -0,0 +1,99 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// Conditions and condition Reasons for the GameServer object.
+const (
+	// PodScheduledCondition reports whether the GameServer's Pod has been placed on a node.
+	PodScheduledCondition clusterv1.ConditionType = "PodScheduled"
+
+	// PortConflictReason (Severity=Info) documents a Pod that could not be scheduled because no node had
+	// the requested host ports free. The Pod is deleted and recreated with a new port.
+	PortConflictReason = "PortConflict"
+
+	// PodUnschedulableReason (Severity=Warning) documents a Pod the scheduler could not place for any reason
+	// other than a port conflict, e.g. missing node capacity or an unsatisfiable node selector.
+	PodUnschedulableReason = "Unschedulable"
+
+	// RescheduleAttemptsExceededReason (Severity=Error) documents a GameServer that hit too many port conflicts
+	// in a row. No further Pods are created for it.
+	RescheduleAttemptsExceededReason = "RescheduleAttemptsExceeded"
+
+	// PendingTimeoutReason (Severity=Warning) documents a Pod that stayed Pending for longer than the
+	// GameServer's PendingTimeout and was deleted.
+	PendingTimeoutReason = "PendingTimeout"
+
+	// ImagePulledCondition reports whether the game server image has been pulled. When the pull fails, the
+	// condition reason is the kubelet's waiting reason, e.g. ErrImagePull or ImagePullBackOff.
+	ImagePulledCondition clusterv1.ConditionType = "ImagePulled"
+
+	// TemplatesRenderedCondition reports whether the GameServer's templated args and env could be expanded.
+	TemplatesRenderedCondition clusterv1.ConditionType = "TemplatesRendered"
+
+	// InvalidTemplateReason (Severity=Error) documents a GameServer with an arg or env value that isn't a
+	// valid template. No Pod is created until it is fixed.
+	InvalidTemplateReason = "InvalidTemplate"
+)
+
+// Conditions and condition Reasons for the Playtest object.
+const (
+	// ServersProvisionedCondition reports whether a GameServer has been created for every group.
+	ServersProvisionedCondition clusterv1.ConditionType = "ServersProvisioned"
+
+	// WaitingForProvisioningReason (Severity=Info) documents a playtest whose start time is still too far out
+	// for its servers to be created.
+	WaitingForProvisioningReason = "WaitingForProvisioning"
+
+	// ProvisioningReason (Severity=Info) documents a playtest that is still creating group servers.
+	ProvisioningReason = "Provisioning"
+
+	// AllGroupsReadyCondition reports whether every group's GameServer is ready to accept players.
+	AllGroupsReadyCondition clusterv1.ConditionType = "AllGroupsReady"
+
+	// WaitingForServersReason (Severity=Info) documents a playtest with group servers that aren't ready yet.
+	WaitingForServersReason = "WaitingForServers"
+
+	// GroupServerFailedReason (Severity=Error) documents a playtest with a group server that failed in a way
+	// that won't resolve on its own, e.g. a missing image.
+	GroupServerFailedReason = "GroupServerFailed"
+
+	// TemplateResolvedCondition reports whether the playtest's PlaytestTemplate could be read.
+	TemplateResolvedCondition clusterv1.ConditionType = "TemplateResolved"
+
+	// TemplateNotFoundReason documents a playtest whose PlaytestTemplate doesn't exist. The playtest keeps
+	// the settings it last resolved (Severity=Warning). If it never resolved any, it waits for the template
+	// until it starts, then runs with its own settings (Severity=Error).
+	TemplateNotFoundReason = "TemplateNotFound"
+)
+
+// Conditions and condition Reasons for the PlaytestSchedule object.
+const (
+	// ScheduleValidCondition reports whether the schedule's cron expression and time zone can be parsed.
+	ScheduleValidCondition clusterv1.ConditionType = "ScheduleValid"
+
+	// InvalidScheduleReason (Severity=Error) documents a schedule whose cron expression can't be parsed.
+	// No Playtests are created until it is fixed.
+	InvalidScheduleReason = "InvalidSchedule"
+
+	// InvalidTimeZoneReason (Severity=Error) documents a schedule whose time zone isn't a known IANA time zone.
+	// No Playtests are created until it is fixed.
+	InvalidTimeZoneReason = "InvalidTimeZone"
+)
@@ -19,11 +19,69 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// PortPolicy describes how a port number is picked from a GameServerPort's range
+// +kubebuilder:validation:Enum=Offset;Random
+type PortPolicy string
+
+const (
+	// PortPolicyOffset picks the port at the same offset into its range as the game port is into the game port range
+	PortPolicyOffset PortPolicy = "Offset"
+
+	// PortPolicyRandom picks a random port from the range
+	PortPolicyRandom PortPolicy = "Random"
+)
+
+// GameServerPort is an additional host port the game server listens on
+type GameServerPort struct {
+	// Name of the port. The built-in game, netimgui and status ports can't be redefined.
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Protocol for the port
+	// +kubebuilder:validation:Enum=UDP;TCP
+	// +kubebuilder:default=TCP
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+
+	// MinPort is the lower bound of the port range
+	MinPort int32 `json:"minPort"`
+
+	// MaxPort is the exclusive upper bound of the port range. Defaults to MinPort plus the size of the game port range.
+	// +optional
+	MaxPort int32 `json:"maxPort,omitempty"`
+
+	// Policy is how the port is picked from its range
+	// +kubebuilder:default=Offset
+	Policy PortPolicy `json:"policy,omitempty"`
+
+	// Arg is the format of the commandline argument used to pass the port to the game server, e.g. "-VoicePort=%d".
+	// It must contain exactly one %d, and %% for a literal %. No argument is passed if empty.
+	// +kubebuilder:validation:Pattern=`^([^%]|%%)*%d([^%]|%%)*$`
+	// +optional
+	Arg string `json:"arg,omitempty"`
+}
+
+// GameServerPlaytest describes the playtest group a GameServer was provisioned for
+type GameServerPlaytest struct {
+	// Name of the Playtest
+	Name string `json:"name"`
+
+	// Group is the name of the playtest group
+	Group string `json:"group,omitempty"`
+
+	// GroupIndex is the position of the group in the playtest, starting at 1
+	GroupIndex int `json:"groupIndex,omitempty"`
+
+	// Users assigned to the group
+	Users []string `json:"users,omitempty"`
+}
+
 // GameServerSpec defines the desired state of GameServer
 type GameServerSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -42,8 +100,27 @@ type GameServerSpec struct {
 	// +kubebuilder:default=false
 	IncludeReadinessProbe bool `json:"includeReadinessProbe,omitempty"`
 
-	// Commandline arguments to start the game server with
+	// Commandline arguments to start the game server with. Each argument is expanded as a Go template
+	// with access to the server name, version, allocated ports and playtest group.
 	CmdArgs []string `json:"cmdArgs,omitempty"`
+
+	// Env is additional environment variables for the game server. Values are expanded like CmdArgs.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Playtest is set when the GameServer was provisioned for a playtest group
+	// +optional
+	Playtest *GameServerPlaytest `json:"playtest,omitempty"`
+
+	// Ports are additional named ports to allocate alongside the built-in game, netimgui and status ports
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Ports []GameServerPort `json:"ports,omitempty"`
+
+	// PendingTimeout is how long the underlying Pod may stay Pending before it is deleted and recreated
+	// +optional
+	PendingTimeout *metav1.Duration `json:"pendingTimeout,omitempty"`
 }
 
 // GameServerStatus defines the observed state of GameServer
@@ -66,6 +143,10 @@ type GameServerStatus struct {
 	// Status port represents the port on which the game server is serving game/session status information
 	StatusPort int32 `json:"statusPort,omitempty"`
 
+	// Ports maps each allocated port name, including the built-in ones, to its port number
+	// +optional
+	Ports map[string]int32 `json:"ports,omitempty"`
+
 	// PodRef refers to the name of the Pod backing the GameServer
 	PodRef *corev1.LocalObjectReference `json:"podRef,omitempty"`
 
@@ -74,6 +155,43 @@ type GameServerStatus struct {
 
 	// Ready is true if the game server is ready to accept traffic
 	Ready bool `json:"ready,omitempty"`
+
+	// RescheduleAttempts is the number of times in a row the underlying Pod was recreated due to a port conflict
+	// +optional
+	RescheduleAttempts int32 `json:"rescheduleAttempts,omitempty"`
+
+	// LastPortConflict is the last time the underlying Pod was recreated due to a port conflict
+	// +optional
+	LastPortConflict *metav1.Time `json:"lastPortConflict,omitempty"`
+
+	// PendingTimeouts is the number of times in a row the underlying Pod was deleted for exceeding PendingTimeout
+	// +optional
+	PendingTimeouts int32 `json:"pendingTimeouts,omitempty"`
+
+	// LastPendingTimeout is the last time the underlying Pod was deleted for exceeding PendingTimeout
+	// +optional
+	LastPendingTimeout *metav1.Time `json:"lastPendingTimeout,omitempty"`
+
+	// Players is how many players the game server reports are connected
+	// +optional
+	Players *int32 `json:"players,omitempty"`
+
+	// AcceptingJoiners is whether the game server reports it will take players joining mid-session
+	// +optional
+	AcceptingJoiners *bool `json:"acceptingJoiners,omitempty"`
+
+	// LastSessionStatusTime is the last time Players and AcceptingJoiners were read from the game server
+	// +optional
+	LastSessionStatusTime *metav1.Time `json:"lastSessionStatusTime,omitempty"`
+
+	// LastSessionStatusPollTime is the last time the game server's status endpoint was polled, whether or
+	// not it answered
+	// +optional
+	LastSessionStatusPollTime *metav1.Time `json:"lastSessionStatusPollTime,omitempty"`
+
+	// Conditions defines current service state of the GameServer
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -101,6 +219,16 @@ type GameServerList struct {
 	Items           []GameServer `json:"items"`
 }
 
+// GetConditions returns the set of conditions for this object.
+func (g *GameServer) GetConditions() clusterv1.Conditions {
+	return g.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (g *GameServer) SetConditions(conditions clusterv1.Conditions) {
+	g.Status.Conditions = conditions
+}
+
 func init() {
 	SchemeBuilder.Register(&GameServer{}, &GameServerList{})
 }
@@ -19,15 +19,103 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 type PlaytestGroup struct {
+	// Name identifies the group. Renaming a group gives it a new server and drains its old one.
 	Name  string   `json:"name,omitempty"`
 	Users []string `json:"users,omitempty"`
+
+	// Version overrides the playtest's Version for this group's server
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Map overrides the playtest's Map for this group's server
+	// +optional
+	Map string `json:"map,omitempty"`
+
+	// GameServerCmdArgs overrides the playtest's GameServerCmdArgs for this group's server when set. An empty
+	// list runs it without any, so it isn't omitted when empty.
+	// +optional
+	GameServerCmdArgs []string `json:"gameServerCmdArgs"`
+
+	// IncludeReadinessProbe overrides the playtest's IncludeReadinessProbe for this group's server
+	// +optional
+	IncludeReadinessProbe *bool `json:"includeReadinessProbe,omitempty"`
+}
+
+// PlaytestGroupExpansion controls when groups are added to or removed from a Playtest beyond MinGroups
+type PlaytestGroupExpansion struct {
+	// MinOpenSlots is the number of open player slots across all groups below which another group is added
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinOpenSlots int `json:"minOpenSlots,omitempty"`
+
+	// CollapseEmptyGroups removes empty groups beyond MinGroups once the playtest has started
+	// +optional
+	CollapseEmptyGroups bool `json:"collapseEmptyGroups,omitempty"`
+}
+
+// PlaytestParty is a set of users who should be placed in the same group
+type PlaytestParty struct {
+	Name  string   `json:"name"`
+	Users []string `json:"users"`
 }
 
+// AssignmentStrategy names how users waiting in UsersToAutoAssign are placed into groups.
+// Operators may register their own strategies alongside the built-in ones.
+type AssignmentStrategy string
+
+const (
+	// AssignmentStrategyRandom places each user in a random group with room
+	AssignmentStrategyRandom AssignmentStrategy = "Random"
+
+	// AssignmentStrategyLeastFilled places each user in the group with the fewest users
+	AssignmentStrategyLeastFilled AssignmentStrategy = "LeastFilled"
+
+	// AssignmentStrategyRoundRobin places each user in the next group with room after the previous user's
+	AssignmentStrategyRoundRobin AssignmentStrategy = "RoundRobin"
+
+	// AssignmentStrategySeeded places each user in a group picked by hashing the user with AssignmentSeed
+	AssignmentStrategySeeded AssignmentStrategy = "Seeded"
+)
+
+// ServerChangePolicy is how a change to a playtest's version or map reaches group servers that are
+// already running, once the playtest has started
+// +kubebuilder:validation:Enum=Block;WhenEmpty;Immediate
+type ServerChangePolicy string
+
+const (
+	// ServerChangePolicyBlock leaves running servers on the old version and map
+	ServerChangePolicyBlock ServerChangePolicy = "Block"
+
+	// ServerChangePolicyWhenEmpty replaces each group's server once it has no players connected, or, if the
+	// server doesn't report its players, once the group has no users
+	ServerChangePolicyWhenEmpty ServerChangePolicy = "WhenEmpty"
+
+	// ServerChangePolicyImmediate replaces every server at once, after the change is confirmed with the
+	// believer.dev/confirm-server-change annotation
+	ServerChangePolicyImmediate ServerChangePolicy = "Immediate"
+)
+
+// ServerChangeState is why a group's server hasn't picked up a version or map change yet
+type ServerChangeState string
+
+const (
+	// ServerChangeBlocked means the playtest's ServerChangePolicy is Block
+	ServerChangeBlocked ServerChangeState = "Blocked"
+
+	// ServerChangeWaitingForEmpty means the server is replaced once it has no players connected, or, if it
+	// doesn't report its players, once the group has no users
+	ServerChangeWaitingForEmpty ServerChangeState = "WaitingForEmpty"
+
+	// ServerChangeWaitingForConfirmation means the server is replaced once the change is confirmed
+	ServerChangeWaitingForConfirmation ServerChangeState = "WaitingForConfirmation"
+)
+
 // PlaytestSpec defines the desired state of Playtest
 type PlaytestSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -41,19 +129,114 @@ type PlaytestSpec struct {
 	StartTime       metav1.Time `json:"startTime,omitempty"`
 	FeedbackURL     string      `json:"feedbackURL,omitempty"`
 
+	// Template is the name of a PlaytestTemplate in the same namespace to take any unset settings from.
+	// Once the playtest has started, changes to the template no longer affect it.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// MaxGroups is the most groups the playtest may expand to. Defaults to MinGroups, i.e. no expansion.
+	// +optional
+	MaxGroups int `json:"maxGroups,omitempty"`
+
+	// GroupExpansion controls when groups are added beyond MinGroups. Groups are always added while
+	// there isn't room for every user waiting to be auto-assigned, up to MaxGroups.
+	// +optional
+	GroupExpansion *PlaytestGroupExpansion `json:"groupExpansion,omitempty"`
+
+	// Spares is how many ready GameServers to keep on standby, on the playtest's version and map. A group
+	// server that dies is swapped for a spare instead of waiting on a new one.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Spares int `json:"spares,omitempty"`
+
+	// LockMembership stops users from being auto-assigned and groups from being added or removed. Anyone
+	// waiting stays queued until membership is unlocked.
+	// +optional
+	LockMembership bool `json:"lockMembership,omitempty"`
+
+	// ServerChangePolicy is how changes to Version or Map, including a group's, reach servers that are
+	// already running once the playtest has started. Before the start they always apply immediately.
+	// Defaults to WhenEmpty.
+	// +optional
+	ServerChangePolicy ServerChangePolicy `json:"serverChangePolicy,omitempty"`
+
+	// Duration is how long the playtest runs after StartTime. Ignored if EndTime is set.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// EndTime is when the playtest is over and its servers are torn down. Defaults to 24 hours after StartTime.
+	// +optional
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+
+	// TeardownGracePeriod is how long group servers are left draining after the end before they are deleted
+	// +optional
+	TeardownGracePeriod *metav1.Duration `json:"teardownGracePeriod,omitempty"`
+
+	// ProvisioningLeadTime is how long before StartTime group servers are created.
+	// Defaults to the operator's -provisioning-lead-time.
+	// +optional
+	ProvisioningLeadTime *metav1.Duration `json:"provisioningLeadTime,omitempty"`
+
+	// Retention is how long the playtest is kept after it ends before it is archived and deleted.
+	// Defaults to the operator's -playtest-retention. Playtests created by a PlaytestSchedule are kept to the
+	// schedule's HistoryLimit instead.
+	// +optional
+	Retention *metav1.Duration `json:"retention,omitempty"`
+
 	// +optional
 	UsersToAutoAssign []string `json:"usersToAutoAssign,omitempty"`
-	GameServerCmdArgs []string `json:"gameServerCmdArgs,omitempty"`
+
+	// PartiesToAutoAssign are parties waiting to be placed into groups. Each party is kept together
+	// unless no group can take all of it, and is waitlisted if it doesn't fit at all.
+	// +optional
+	PartiesToAutoAssign []PlaytestParty `json:"partiesToAutoAssign,omitempty"`
+
+	// AssignmentStrategy is how users and parties waiting to be auto-assigned are placed into groups.
+	// Defaults to Random.
+	// +optional
+	AssignmentStrategy AssignmentStrategy `json:"assignmentStrategy,omitempty"`
+
+	// AssignmentSeed makes the Seeded assignment strategy pick different groups for the same users
+	// +optional
+	AssignmentSeed int64 `json:"assignmentSeed,omitempty"`
+
+	// GameServerCmdArgs are passed to each group's server. When set they override the template's. An empty
+	// list runs the servers without any, so it isn't omitted when empty.
+	// +optional
+	GameServerCmdArgs []string `json:"gameServerCmdArgs"`
 
 	Groups []PlaytestGroup `json:"groups,omitempty"`
 
-	// IncludeReadinessProbe is true if the game server should include a readiness probe
-	// +kubebuilder:default=false
-	IncludeReadinessProbe bool `json:"includeReadinessProbe,omitempty"`
+	// IncludeReadinessProbe is true if the game servers should include a readiness probe. When unset, the
+	// template's setting is used, and otherwise it's off.
+	// +optional
+	IncludeReadinessProbe *bool `json:"includeReadinessProbe,omitempty"`
 
 	// DisableGameServers is true if game servers should not be created for this playtest
 	// +kubebuilder:default=false
 	DisableGameServers bool `json:"disableGameServers,omitempty"`
+
+	// Suspend pauses the playtest. Its groups, assignments and servers are kept, but no servers are
+	// created, users aren't auto-assigned and it isn't pruned until it's resumed.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DrainOnSuspend drains and deletes the playtest's servers while it's suspended, after
+	// TeardownGracePeriod. They're recreated once it's resumed.
+	// +optional
+	DrainOnSuspend bool `json:"drainOnSuspend,omitempty"`
+
+	// GameServerEnv is additional environment variables passed through to each group's GameServer
+	// +optional
+	GameServerEnv []corev1.EnvVar `json:"gameServerEnv,omitempty"`
+
+	// GameServerPorts are additional named ports passed through to each group's GameServer
+	// +optional
+	GameServerPorts []GameServerPort `json:"gameServerPorts,omitempty"`
+
+	// PendingTimeout is passed through to each group's GameServer
+	// +optional
+	PendingTimeout *metav1.Duration `json:"pendingTimeout,omitempty"`
 }
 
 type PlaytestGroupStatus struct {
@@ -61,6 +244,185 @@ type PlaytestGroupStatus struct {
 	ServerRef *corev1.LocalObjectReference `json:"serverRef,omitempty"`
 	Users     []string                     `json:"users,omitempty"`
 	Ready     bool                         `json:"ready,omitempty"`
+
+	// ServerName is the name the group's GameServer is created with. It is kept for the life of the
+	// group, so changes to how names are derived don't orphan existing servers.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// Version is the version the group's server is running
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Map is the map the group's server is running
+	// +optional
+	Map string `json:"map,omitempty"`
+
+	// ServerChange is why the group's server is still on an old version or map, if it is: WaitingForEmpty
+	// while players are still connected, Blocked or WaitingForConfirmation
+	// +optional
+	ServerChange ServerChangeState `json:"serverChange,omitempty"`
+
+	// Address is the host and port players connect to the group's server on
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Players is how many players the group's server reports are connected
+	// +optional
+	Players *int32 `json:"players,omitempty"`
+
+	// AcceptingJoiners is whether the group's server reports it will take players joining mid-session
+	// +optional
+	AcceptingJoiners *bool `json:"acceptingJoiners,omitempty"`
+
+	// Reason is the reason the group's server is failing to come up, if any
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human-readable description of Reason
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Outcome is the state of the group's server when the playtest ended, kept after the server is torn down
+	// +optional
+	Outcome *PlaytestGroupOutcome `json:"outcome,omitempty"`
+}
+
+// PlaytestGroupOutcome is the state a group's server was in when the playtest ended
+type PlaytestGroupOutcome struct {
+	// Server is the name of the group's GameServer
+	// +optional
+	Server string `json:"server,omitempty"`
+
+	// Ready is whether the group's server was ready
+	Ready bool `json:"ready"`
+
+	// Reason is the reason the group's server was failing, if it was
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human-readable description of Reason
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// PlaytestPhase is a label for the lifecycle stage of a Playtest
+// +kubebuilder:validation:Enum=Scheduled;Provisioning;Ready;InProgress;Ended;Failed;Suspended
+type PlaytestPhase string
+
+const (
+	// PlaytestPhaseScheduled means the playtest is too far out for servers to be provisioned
+	PlaytestPhaseScheduled PlaytestPhase = "Scheduled"
+
+	// PlaytestPhaseProvisioning means group servers are being created but aren't all ready
+	PlaytestPhaseProvisioning PlaytestPhase = "Provisioning"
+
+	// PlaytestPhaseReady means every group server is ready and the playtest hasn't started yet
+	PlaytestPhaseReady PlaytestPhase = "Ready"
+
+	// PlaytestPhaseInProgress means the playtest has started
+	PlaytestPhaseInProgress PlaytestPhase = "InProgress"
+
+	// PlaytestPhaseEnded means the playtest is over
+	PlaytestPhaseEnded PlaytestPhase = "Ended"
+
+	// PlaytestPhaseFailed means at least one group server failed in a way that won't resolve on its own
+	PlaytestPhaseFailed PlaytestPhase = "Failed"
+
+	// PlaytestPhaseSuspended means the playtest is suspended and no servers are being created
+	PlaytestPhaseSuspended PlaytestPhase = "Suspended"
+)
+
+// PlaytestAssignment records which group a user was auto-assigned to
+type PlaytestAssignment struct {
+	User     string             `json:"user"`
+	Group    string             `json:"group"`
+	Strategy AssignmentStrategy `json:"strategy"`
+	Time     metav1.Time        `json:"time"`
+
+	// Party is the party the user was assigned with, if any
+	// +optional
+	Party string `json:"party,omitempty"`
+}
+
+// PlaytestWaitlistEntry is a user or party that couldn't be auto-assigned, waiting for room in a group
+type PlaytestWaitlistEntry struct {
+	Users  []string    `json:"users"`
+	Reason string      `json:"reason"`
+	Since  metav1.Time `json:"since"`
+
+	// Party is the name of the party, if the entry is one
+	// +optional
+	Party string `json:"party,omitempty"`
+}
+
+// PlaytestTemplateStatus records the template settings a Playtest was resolved with
+type PlaytestTemplateStatus struct {
+	// Name is the PlaytestTemplate the settings came from
+	Name string `json:"name"`
+
+	// Generation is the generation of the PlaytestTemplate the settings came from
+	Generation int64 `json:"generation"`
+
+	// Spec is the playtest's settings after filling in the template's
+	Spec PlaytestTemplateSpec `json:"spec"`
+}
+
+// PlaytestPhaseTransition records when a Playtest last entered a phase
+type PlaytestPhaseTransition struct {
+	Phase PlaytestPhase `json:"phase"`
+	Time  metav1.Time   `json:"time"`
+}
+
+// PlaytestGroupMove is a user moved between groups by a rebalance
+type PlaytestGroupMove struct {
+	User string `json:"user"`
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Party is the party the user was moved with, if any
+	// +optional
+	Party string `json:"party,omitempty"`
+}
+
+// PlaytestRebalance records the moves made by a rebalance
+type PlaytestRebalance struct {
+	Time metav1.Time `json:"time"`
+
+	// +optional
+	Moves []PlaytestGroupMove `json:"moves,omitempty"`
+}
+
+// PlaytestLateJoiner is a user auto-assigned after the playtest started, and where to connect
+type PlaytestLateJoiner struct {
+	User  string      `json:"user"`
+	Group string      `json:"group"`
+	Time  metav1.Time `json:"time"`
+
+	// Server is the name of the group's GameServer
+	// +optional
+	Server string `json:"server,omitempty"`
+
+	// Address is the host and port to connect to, once the group's server has one
+	// +optional
+	Address string `json:"address,omitempty"`
+}
+
+// PlaytestActionResult records the outcome of a one-shot action annotation
+type PlaytestActionResult struct {
+	// Action is the annotation that requested the action
+	Action string `json:"action"`
+
+	// Value is the annotation's value
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	Time      metav1.Time `json:"time"`
+	Succeeded bool        `json:"succeeded"`
+
+	// Message describes what the action did, or why it failed
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // PlaytestStatus defines the observed state of Playtest
@@ -68,10 +430,56 @@ type PlaytestStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	Groups []PlaytestGroupStatus `json:"groups,omitempty"`
+
+	// Phase is the current lifecycle stage of the playtest
+	// +optional
+	Phase PlaytestPhase `json:"phase,omitempty"`
+
+	// PhaseTransitions records the last time the playtest entered each phase
+	// +optional
+	PhaseTransitions []PlaytestPhaseTransition `json:"phaseTransitions,omitempty"`
+
+	// Template is the resolved template configuration, frozen once the playtest starts
+	// +optional
+	Template *PlaytestTemplateStatus `json:"template,omitempty"`
+
+	// Assignments records the latest auto-assignment of each user
+	// +optional
+	Assignments []PlaytestAssignment `json:"assignments,omitempty"`
+
+	// Waitlist holds users and parties that couldn't be auto-assigned, and why. They are placed ahead of
+	// anyone still queued as soon as there's room.
+	// +optional
+	Waitlist []PlaytestWaitlistEntry `json:"waitlist,omitempty"`
+
+	// LateJoiners are the users auto-assigned after the playtest started, with their connect info
+	// +optional
+	LateJoiners []PlaytestLateJoiner `json:"lateJoiners,omitempty"`
+
+	// Actions records the outcome of the latest run of each action annotation
+	// +optional
+	Actions []PlaytestActionResult `json:"actions,omitempty"`
+
+	// LastRebalance records the moves made by the latest rebalance
+	// +optional
+	LastRebalance *PlaytestRebalance `json:"lastRebalance,omitempty"`
+
+	// LastProvisioningBatchTime is when the last batch of group servers was created
+	// +optional
+	LastProvisioningBatchTime *metav1.Time `json:"lastProvisioningBatchTime,omitempty"`
+
+	// Conditions defines current service state of the Playtest
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Start",type=date,JSONPath=`.spec.startTime`
+//+kubebuilder:printcolumn:name="Servers Provisioned",type=string,JSONPath=`.status.conditions[?(@.type=="ServersProvisioned")].status`
+//+kubebuilder:printcolumn:name="All Groups Ready",type=string,JSONPath=`.status.conditions[?(@.type=="AllGroupsReady")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Playtest is the Schema for the playtests API
 type Playtest struct {
@@ -91,6 +499,16 @@ type PlaytestList struct {
 	Items           []Playtest `json:"items"`
 }
 
+// GetConditions returns the set of conditions for this object.
+func (p *Playtest) GetConditions() clusterv1.Conditions {
+	return p.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (p *Playtest) SetConditions(conditions clusterv1.Conditions) {
+	p.Status.Conditions = conditions
+}
+
 func init() {
 	SchemeBuilder.Register(&Playtest{}, &PlaytestList{})
 }
@@ -0,0 +1,134 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// PlaytestTemplateMetadata is the labels and annotations of Playtests created from a template
+type PlaytestTemplateMetadata struct {
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PlaytestScheduleTemplate describes the Playtest created for each occurrence of a schedule
+type PlaytestScheduleTemplate struct {
+	// +optional
+	Metadata PlaytestTemplateMetadata `json:"metadata,omitempty"`
+
+	// Spec is the spec of each Playtest. Its StartTime is set to the occurrence, so the end should be
+	// given as a Duration rather than an EndTime.
+	Spec PlaytestSpec `json:"spec"`
+}
+
+// PlaytestScheduleSpec defines the desired state of PlaytestSchedule
+type PlaytestScheduleSpec struct {
+	// Schedule is a standard cron expression for when each playtest starts, e.g. "0 17 * * 2,4"
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// TimeZone is the IANA time zone Schedule is interpreted in, e.g. "America/Los_Angeles". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// CreateBefore is how long before each occurrence its Playtest is created. It should be longer than
+	// the playtest's provisioning lead time. Defaults to 1 hour.
+	// +optional
+	CreateBefore *metav1.Duration `json:"createBefore,omitempty"`
+
+	// Suspend pauses the schedule. No Playtests are created while it is set.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SkipOccurrences are start times for which no Playtest is created
+	// +optional
+	SkipOccurrences []metav1.Time `json:"skipOccurrences,omitempty"`
+
+	// HistoryLimit is how many ended Playtests created by this schedule are kept. Older ones are archived and
+	// deleted. Defaults to 3.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+
+	// Template is the Playtest created for each occurrence
+	Template PlaytestScheduleTemplate `json:"template"`
+}
+
+// PlaytestScheduleStatus defines the observed state of PlaytestSchedule
+type PlaytestScheduleStatus struct {
+	// LastScheduleTime is the start time of the most recent Playtest created by the schedule
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// NextScheduleTime is the start time of the next Playtest the schedule will create
+	// +optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// Playtests are the Playtests created by the schedule that still exist
+	// +optional
+	Playtests []corev1.LocalObjectReference `json:"playtests,omitempty"`
+
+	// Conditions defines current service state of the PlaytestSchedule
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Time Zone",type=string,JSONPath=`.spec.timeZone`
+//+kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+//+kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+//+kubebuilder:printcolumn:name="Next Schedule",type=string,JSONPath=`.status.nextScheduleTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PlaytestSchedule is the Schema for the playtestschedules API
+type PlaytestSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PlaytestScheduleSpec   `json:"spec,omitempty"`
+	Status PlaytestScheduleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PlaytestScheduleList contains a list of PlaytestSchedule
+type PlaytestScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PlaytestSchedule `json:"items"`
+}
+
+// GetConditions returns the set of conditions for this object.
+func (s *PlaytestSchedule) GetConditions() clusterv1.Conditions {
+	return s.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (s *PlaytestSchedule) SetConditions(conditions clusterv1.Conditions) {
+	s.Status.Conditions = conditions
+}
+
+func init() {
+	SchemeBuilder.Register(&PlaytestSchedule{}, &PlaytestScheduleList{})
+}
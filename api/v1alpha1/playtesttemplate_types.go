@@ -0,0 +1,75 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PlaytestTemplateSpec defines the settings shared by Playtests that reference the template. Any of them
+// set on a Playtest take precedence.
+type PlaytestTemplateSpec struct {
+	// +optional
+	Map string `json:"map,omitempty"`
+	// +optional
+	MinGroups int `json:"minGroups,omitempty"`
+	// +optional
+	PlayersPerGroup int `json:"playersPerGroup,omitempty"`
+	// +optional
+	FeedbackURL string `json:"feedbackURL,omitempty"`
+	// +optional
+	GameServerCmdArgs []string `json:"gameServerCmdArgs,omitempty"`
+
+	// IncludeReadinessProbe is true if the game servers should include a readiness probe
+	// +optional
+	IncludeReadinessProbe bool `json:"includeReadinessProbe,omitempty"`
+
+	// +optional
+	GameServerEnv []corev1.EnvVar `json:"gameServerEnv,omitempty"`
+	// +optional
+	GameServerPorts []GameServerPort `json:"gameServerPorts,omitempty"`
+	// +optional
+	PendingTimeout *metav1.Duration `json:"pendingTimeout,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Map",type=string,JSONPath=`.spec.map`
+//+kubebuilder:printcolumn:name="Min Groups",type=integer,JSONPath=`.spec.minGroups`
+//+kubebuilder:printcolumn:name="Players Per Group",type=integer,JSONPath=`.spec.playersPerGroup`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PlaytestTemplate is the Schema for the playtesttemplates API
+type PlaytestTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PlaytestTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PlaytestTemplateList contains a list of PlaytestTemplate
+type PlaytestTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PlaytestTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PlaytestTemplate{}, &PlaytestTemplateList{})
+}
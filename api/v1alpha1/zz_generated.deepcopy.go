@@ -22,7 +22,9 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -84,6 +86,41 @@ func (in *GameServerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerPlaytest) DeepCopyInto(out *GameServerPlaytest) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerPlaytest.
+func (in *GameServerPlaytest) DeepCopy() *GameServerPlaytest {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerPlaytest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerPort) DeepCopyInto(out *GameServerPort) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerPort.
+func (in *GameServerPort) DeepCopy() *GameServerPort {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerSpec) DeepCopyInto(out *GameServerSpec) {
 	*out = *in
@@ -92,6 +129,28 @@ func (in *GameServerSpec) DeepCopyInto(out *GameServerSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Playtest != nil {
+		in, out := &in.Playtest, &out.Playtest
+		*out = new(GameServerPlaytest)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]GameServerPort, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingTimeout != nil {
+		in, out := &in.PendingTimeout, &out.PendingTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerSpec.
@@ -107,6 +166,13 @@ func (in *GameServerSpec) DeepCopy() *GameServerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerStatus) DeepCopyInto(out *GameServerStatus) {
 	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.PodRef != nil {
 		in, out := &in.PodRef, &out.PodRef
 		*out = new(v1.LocalObjectReference)
@@ -117,6 +183,39 @@ func (in *GameServerStatus) DeepCopyInto(out *GameServerStatus) {
 		*out = new(v1.PodStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.LastPortConflict != nil {
+		in, out := &in.LastPortConflict, &out.LastPortConflict
+		*out = (*in).DeepCopy()
+	}
+	if in.LastPendingTimeout != nil {
+		in, out := &in.LastPendingTimeout, &out.LastPendingTimeout
+		*out = (*in).DeepCopy()
+	}
+	if in.Players != nil {
+		in, out := &in.Players, &out.Players
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AcceptingJoiners != nil {
+		in, out := &in.AcceptingJoiners, &out.AcceptingJoiners
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LastSessionStatusTime != nil {
+		in, out := &in.LastSessionStatusTime, &out.LastSessionStatusTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSessionStatusPollTime != nil {
+		in, out := &in.LastSessionStatusPollTime, &out.LastSessionStatusPollTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerStatus.
@@ -156,6 +255,38 @@ func (in *Playtest) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestActionResult) DeepCopyInto(out *PlaytestActionResult) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestActionResult.
+func (in *PlaytestActionResult) DeepCopy() *PlaytestActionResult {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestActionResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestAssignment) DeepCopyInto(out *PlaytestAssignment) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestAssignment.
+func (in *PlaytestAssignment) DeepCopy() *PlaytestAssignment {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestAssignment)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlaytestGroup) DeepCopyInto(out *PlaytestGroup) {
 	*out = *in
@@ -164,6 +295,16 @@ func (in *PlaytestGroup) DeepCopyInto(out *PlaytestGroup) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.GameServerCmdArgs != nil {
+		in, out := &in.GameServerCmdArgs, &out.GameServerCmdArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeReadinessProbe != nil {
+		in, out := &in.IncludeReadinessProbe, &out.IncludeReadinessProbe
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestGroup.
@@ -176,6 +317,51 @@ func (in *PlaytestGroup) DeepCopy() *PlaytestGroup {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestGroupExpansion) DeepCopyInto(out *PlaytestGroupExpansion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestGroupExpansion.
+func (in *PlaytestGroupExpansion) DeepCopy() *PlaytestGroupExpansion {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestGroupExpansion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestGroupMove) DeepCopyInto(out *PlaytestGroupMove) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestGroupMove.
+func (in *PlaytestGroupMove) DeepCopy() *PlaytestGroupMove {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestGroupMove)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestGroupOutcome) DeepCopyInto(out *PlaytestGroupOutcome) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestGroupOutcome.
+func (in *PlaytestGroupOutcome) DeepCopy() *PlaytestGroupOutcome {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestGroupOutcome)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlaytestGroupStatus) DeepCopyInto(out *PlaytestGroupStatus) {
 	*out = *in
@@ -189,6 +375,21 @@ func (in *PlaytestGroupStatus) DeepCopyInto(out *PlaytestGroupStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Players != nil {
+		in, out := &in.Players, &out.Players
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AcceptingJoiners != nil {
+		in, out := &in.AcceptingJoiners, &out.AcceptingJoiners
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Outcome != nil {
+		in, out := &in.Outcome, &out.Outcome
+		*out = new(PlaytestGroupOutcome)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestGroupStatus.
@@ -201,6 +402,22 @@ func (in *PlaytestGroupStatus) DeepCopy() *PlaytestGroupStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestLateJoiner) DeepCopyInto(out *PlaytestLateJoiner) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestLateJoiner.
+func (in *PlaytestLateJoiner) DeepCopy() *PlaytestLateJoiner {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestLateJoiner)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlaytestList) DeepCopyInto(out *PlaytestList) {
 	*out = *in
@@ -233,15 +450,252 @@ func (in *PlaytestList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestParty) DeepCopyInto(out *PlaytestParty) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestParty.
+func (in *PlaytestParty) DeepCopy() *PlaytestParty {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestParty)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestPhaseTransition) DeepCopyInto(out *PlaytestPhaseTransition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestPhaseTransition.
+func (in *PlaytestPhaseTransition) DeepCopy() *PlaytestPhaseTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestPhaseTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestRebalance) DeepCopyInto(out *PlaytestRebalance) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Moves != nil {
+		in, out := &in.Moves, &out.Moves
+		*out = make([]PlaytestGroupMove, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestRebalance.
+func (in *PlaytestRebalance) DeepCopy() *PlaytestRebalance {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestRebalance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestSchedule) DeepCopyInto(out *PlaytestSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestSchedule.
+func (in *PlaytestSchedule) DeepCopy() *PlaytestSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlaytestSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestScheduleList) DeepCopyInto(out *PlaytestScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PlaytestSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestScheduleList.
+func (in *PlaytestScheduleList) DeepCopy() *PlaytestScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlaytestScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestScheduleSpec) DeepCopyInto(out *PlaytestScheduleSpec) {
+	*out = *in
+	if in.CreateBefore != nil {
+		in, out := &in.CreateBefore, &out.CreateBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.SkipOccurrences != nil {
+		in, out := &in.SkipOccurrences, &out.SkipOccurrences
+		*out = make([]metav1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HistoryLimit != nil {
+		in, out := &in.HistoryLimit, &out.HistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestScheduleSpec.
+func (in *PlaytestScheduleSpec) DeepCopy() *PlaytestScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestScheduleStatus) DeepCopyInto(out *PlaytestScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Playtests != nil {
+		in, out := &in.Playtests, &out.Playtests
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestScheduleStatus.
+func (in *PlaytestScheduleStatus) DeepCopy() *PlaytestScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestScheduleTemplate) DeepCopyInto(out *PlaytestScheduleTemplate) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestScheduleTemplate.
+func (in *PlaytestScheduleTemplate) DeepCopy() *PlaytestScheduleTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestScheduleTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlaytestSpec) DeepCopyInto(out *PlaytestSpec) {
 	*out = *in
 	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.GroupExpansion != nil {
+		in, out := &in.GroupExpansion, &out.GroupExpansion
+		*out = new(PlaytestGroupExpansion)
+		**out = **in
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.TeardownGracePeriod != nil {
+		in, out := &in.TeardownGracePeriod, &out.TeardownGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ProvisioningLeadTime != nil {
+		in, out := &in.ProvisioningLeadTime, &out.ProvisioningLeadTime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.UsersToAutoAssign != nil {
 		in, out := &in.UsersToAutoAssign, &out.UsersToAutoAssign
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PartiesToAutoAssign != nil {
+		in, out := &in.PartiesToAutoAssign, &out.PartiesToAutoAssign
+		*out = make([]PlaytestParty, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.GameServerCmdArgs != nil {
 		in, out := &in.GameServerCmdArgs, &out.GameServerCmdArgs
 		*out = make([]string, len(*in))
@@ -254,6 +708,28 @@ func (in *PlaytestSpec) DeepCopyInto(out *PlaytestSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.IncludeReadinessProbe != nil {
+		in, out := &in.IncludeReadinessProbe, &out.IncludeReadinessProbe
+		*out = new(bool)
+		**out = **in
+	}
+	if in.GameServerEnv != nil {
+		in, out := &in.GameServerEnv, &out.GameServerEnv
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GameServerPorts != nil {
+		in, out := &in.GameServerPorts, &out.GameServerPorts
+		*out = make([]GameServerPort, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingTimeout != nil {
+		in, out := &in.PendingTimeout, &out.PendingTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestSpec.
@@ -276,6 +752,62 @@ func (in *PlaytestStatus) DeepCopyInto(out *PlaytestStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PhaseTransitions != nil {
+		in, out := &in.PhaseTransitions, &out.PhaseTransitions
+		*out = make([]PlaytestPhaseTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(PlaytestTemplateStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Assignments != nil {
+		in, out := &in.Assignments, &out.Assignments
+		*out = make([]PlaytestAssignment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Waitlist != nil {
+		in, out := &in.Waitlist, &out.Waitlist
+		*out = make([]PlaytestWaitlistEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LateJoiners != nil {
+		in, out := &in.LateJoiners, &out.LateJoiners
+		*out = make([]PlaytestLateJoiner, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]PlaytestActionResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastRebalance != nil {
+		in, out := &in.LastRebalance, &out.LastRebalance
+		*out = new(PlaytestRebalance)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastProvisioningBatchTime != nil {
+		in, out := &in.LastProvisioningBatchTime, &out.LastProvisioningBatchTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestStatus.
@@ -287,3 +819,164 @@ func (in *PlaytestStatus) DeepCopy() *PlaytestStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestTemplate) DeepCopyInto(out *PlaytestTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestTemplate.
+func (in *PlaytestTemplate) DeepCopy() *PlaytestTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlaytestTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestTemplateList) DeepCopyInto(out *PlaytestTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PlaytestTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestTemplateList.
+func (in *PlaytestTemplateList) DeepCopy() *PlaytestTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlaytestTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestTemplateMetadata) DeepCopyInto(out *PlaytestTemplateMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestTemplateMetadata.
+func (in *PlaytestTemplateMetadata) DeepCopy() *PlaytestTemplateMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestTemplateMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestTemplateSpec) DeepCopyInto(out *PlaytestTemplateSpec) {
+	*out = *in
+	if in.GameServerCmdArgs != nil {
+		in, out := &in.GameServerCmdArgs, &out.GameServerCmdArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GameServerEnv != nil {
+		in, out := &in.GameServerEnv, &out.GameServerEnv
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GameServerPorts != nil {
+		in, out := &in.GameServerPorts, &out.GameServerPorts
+		*out = make([]GameServerPort, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingTimeout != nil {
+		in, out := &in.PendingTimeout, &out.PendingTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestTemplateSpec.
+func (in *PlaytestTemplateSpec) DeepCopy() *PlaytestTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestTemplateStatus) DeepCopyInto(out *PlaytestTemplateStatus) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestTemplateStatus.
+func (in *PlaytestTemplateStatus) DeepCopy() *PlaytestTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlaytestWaitlistEntry) DeepCopyInto(out *PlaytestWaitlistEntry) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlaytestWaitlistEntry.
+func (in *PlaytestWaitlistEntry) DeepCopy() *PlaytestWaitlistEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(PlaytestWaitlistEntry)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -65,6 +65,14 @@ func main() {
 	var gamePortMax int
 	var netimguiPortMin int
 	var statusPortMin int
+	var maxRescheduleAttempts int
+	var rescheduleBackoff time.Duration
+	var maxRescheduleBackoff time.Duration
+	var playtestRetention time.Duration
+	var provisioningLeadTime time.Duration
+	var provisioningBatchSize int
+	var provisioningBatchInterval time.Duration
+	var sessionStatusInterval time.Duration
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -76,6 +84,14 @@ func main() {
 	flag.IntVar(&gamePortMax, "game-port-max", 7800, "upper bound of game port range")
 	flag.IntVar(&netimguiPortMin, "netimgui-port-min", 7800, "lower bound of netimgui port range")
 	flag.IntVar(&statusPortMin, "status-port-min", 9000, "lower bound of status port range")
+	flag.IntVar(&maxRescheduleAttempts, "max-reschedule-attempts", 10, "consecutive port conflicts before a game server is marked unschedulable (0 retries forever)")
+	flag.DurationVar(&rescheduleBackoff, "reschedule-backoff", 1*time.Second, "delay before rescheduling a game server after its first port conflict")
+	flag.DurationVar(&maxRescheduleBackoff, "max-reschedule-backoff", 1*time.Minute, "upper bound of the delay between port conflict reschedules")
+	flag.DurationVar(&playtestRetention, "playtest-retention", 24*time.Hour, "how long ended playtests are kept before being archived and deleted")
+	flag.DurationVar(&provisioningLeadTime, "provisioning-lead-time", 10*time.Minute, "how long before a playtest's start time its group servers are created")
+	flag.IntVar(&provisioningBatchSize, "provisioning-batch-size", 5, "how many playtest group servers are created at a time (0 creates them all at once)")
+	flag.DurationVar(&provisioningBatchInterval, "provisioning-batch-interval", 30*time.Second, "delay between batches of playtest group servers")
+	flag.DurationVar(&sessionStatusInterval, "session-status-interval", 30*time.Second, "how often ready game servers are polled for their player count (0 disables polling)")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -125,6 +141,12 @@ func main() {
 		GamePortMax:     int32(gamePortMax),
 		NetImguiPortMin: int32(netimguiPortMin),
 		StatusPortMin:   int32(statusPortMin),
+
+		MaxRescheduleAttempts: int32(maxRescheduleAttempts),
+		RescheduleBackoff:     rescheduleBackoff,
+		MaxRescheduleBackoff:  maxRescheduleBackoff,
+		Recorder:              mgr.GetEventRecorderFor("gameserver-controller"),
+		SessionStatusInterval: sessionStatusInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GameServer")
 		os.Exit(1)
@@ -132,10 +154,23 @@ func main() {
 	if err = (&controller.PlaytestReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
+
+		DefaultRetention:            playtestRetention,
+		DefaultProvisioningLeadTime: provisioningLeadTime,
+		ProvisioningBatchSize:       provisioningBatchSize,
+		ProvisioningBatchInterval:   provisioningBatchInterval,
+		Recorder:                    mgr.GetEventRecorderFor("playtest-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Playtest")
 		os.Exit(1)
 	}
+	if err = (&controller.PlaytestScheduleReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PlaytestSchedule")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,313 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// One-shot action annotations. The controller carries each out once, removes the annotation and records
+// the outcome as an Event and in the playtest's status.
+const (
+	// RestartGroupAnnotation restarts the server of the named group
+	RestartGroupAnnotation = "believer.dev/restart-group"
+
+	// ReshuffleAnnotation puts every group's users back in the queue to be auto-assigned again.
+	// Parties are kept together.
+	ReshuffleAnnotation = "believer.dev/reshuffle"
+
+	// RebalanceAnnotation evens out group sizes by moving users from the fullest groups to the emptiest.
+	// Parties are moved as a whole.
+	RebalanceAnnotation = "believer.dev/rebalance"
+
+	// StartNowAnnotation moves the start time up to now
+	StartNowAnnotation = "believer.dev/start-now"
+
+	// ExtendEndAnnotation adds the given number of minutes to the extend-by annotation
+	ExtendEndAnnotation = "believer.dev/extend-end"
+
+	// LockMembershipAnnotation sets LockMembership to the given boolean
+	LockMembershipAnnotation = "believer.dev/lock-membership"
+)
+
+// RestartAnnotation restarts a GameServer's Pod. It is removed once the Pod has been deleted.
+const RestartAnnotation = "believer.dev/restart"
+
+// RestartedReason is the Event reason recorded when a GameServer's Pod is restarted
+const RestartedReason = "Restarted"
+
+const (
+	// ActionSucceededReason is the Event reason recorded when an action annotation was carried out
+	ActionSucceededReason = "ActionSucceeded"
+
+	// ActionFailedReason is the Event reason recorded when an action annotation couldn't be carried out
+	ActionFailedReason = "ActionFailed"
+)
+
+// playtestAction carries out an action annotation with the given value, returning a description of
+// what it did
+type playtestAction func(r *PlaytestReconciler, ctx context.Context, playtest *gamev1alpha1.Playtest, value string, now time.Time) (string, error)
+
+// playtestActions are the supported action annotations, in the order they're carried out. Actions that
+// reach outside the playtest are only carried out once the annotation's removal is saved, so a retried
+// reconcile can't carry them out twice.
+var playtestActions = []struct {
+	annotation string
+	run        playtestAction
+	afterSave  bool
+}{
+	{LockMembershipAnnotation, (*PlaytestReconciler).lockMembership, false},
+	{StartNowAnnotation, (*PlaytestReconciler).startNow, false},
+	{ExtendEndAnnotation, (*PlaytestReconciler).extendEnd, false},
+	{ReshuffleAnnotation, (*PlaytestReconciler).reshuffle, false},
+	{RebalanceAnnotation, (*PlaytestReconciler).rebalance, false},
+	{RestartGroupAnnotation, (*PlaytestReconciler).restartGroup, true},
+}
+
+// pendingAction is an action whose annotation has been removed, waiting for that to be saved
+type pendingAction struct {
+	annotation string
+	value      string
+	run        playtestAction
+}
+
+// runActions removes the action annotations set on the playtest and carries out the ones that only change
+// the playtest, recording their outcomes in its status. The results are returned so Events can be recorded
+// once the changes are saved, along with the actions to carry out after that with runPendingActions.
+func (r *PlaytestReconciler) runActions(ctx context.Context, playtest *gamev1alpha1.Playtest) ([]gamev1alpha1.PlaytestActionResult, []pendingAction) {
+	now := time.Now()
+
+	results := []gamev1alpha1.PlaytestActionResult{}
+	pending := []pendingAction{}
+	for _, action := range playtestActions {
+		value, ok := playtest.GetAnnotations()[action.annotation]
+		if !ok {
+			continue
+		}
+
+		delete(playtest.Annotations, action.annotation)
+
+		if action.afterSave {
+			pending = append(pending, pendingAction{annotation: action.annotation, value: value, run: action.run})
+			continue
+		}
+
+		results = append(results, r.runAction(ctx, playtest, pendingAction{annotation: action.annotation, value: value, run: action.run}, now))
+	}
+
+	return results, pending
+}
+
+// runPendingActions carries out the actions held back by runActions, recording their outcomes in the
+// playtest's status
+func (r *PlaytestReconciler) runPendingActions(ctx context.Context, playtest *gamev1alpha1.Playtest, pending []pendingAction) []gamev1alpha1.PlaytestActionResult {
+	now := time.Now()
+
+	results := []gamev1alpha1.PlaytestActionResult{}
+	for _, action := range pending {
+		results = append(results, r.runAction(ctx, playtest, action, now))
+	}
+
+	return results
+}
+
+// runAction carries out a single action and records its outcome in the playtest's status
+func (r *PlaytestReconciler) runAction(ctx context.Context, playtest *gamev1alpha1.Playtest, action pendingAction, now time.Time) gamev1alpha1.PlaytestActionResult {
+	log := log.FromContext(ctx)
+
+	result := gamev1alpha1.PlaytestActionResult{
+		Action: action.annotation,
+		Value:  action.value,
+		Time:   metav1.NewTime(now),
+	}
+
+	message, err := action.run(r, ctx, playtest, action.value, now)
+	if err != nil {
+		log.Info("action failed", "action", action.annotation, "value", action.value, "reason", err.Error())
+		result.Message = err.Error()
+	} else {
+		log.Info("action succeeded", "action", action.annotation, "value", action.value)
+		result.Succeeded = true
+		result.Message = message
+	}
+
+	recordActionResult(playtest, result)
+
+	return result
+}
+
+// recordActionResult keeps only the latest result of each action in the playtest's status
+func recordActionResult(playtest *gamev1alpha1.Playtest, result gamev1alpha1.PlaytestActionResult) {
+	actions := []gamev1alpha1.PlaytestActionResult{}
+	for _, action := range playtest.Status.Actions {
+		if action.Action != result.Action {
+			actions = append(actions, action)
+		}
+	}
+
+	playtest.Status.Actions = append(actions, result)
+}
+
+// recordActionEvents records an Event on the playtest for each action result
+func (r *PlaytestReconciler) recordActionEvents(playtest *gamev1alpha1.Playtest, results []gamev1alpha1.PlaytestActionResult) {
+	if r.Recorder == nil {
+		return
+	}
+
+	for _, result := range results {
+		if result.Succeeded {
+			r.Recorder.Eventf(playtest, corev1.EventTypeNormal, ActionSucceededReason, "%s: %s", result.Action, result.Message)
+		} else {
+			r.Recorder.Eventf(playtest, corev1.EventTypeWarning, ActionFailedReason, "%s: %s", result.Action, result.Message)
+		}
+	}
+}
+
+func (r *PlaytestReconciler) lockMembership(_ context.Context, playtest *gamev1alpha1.Playtest, value string, _ time.Time) (string, error) {
+	locked, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", fmt.Errorf("%q is not true or false", value)
+	}
+
+	playtest.Spec.LockMembership = locked
+	if locked {
+		return "membership locked", nil
+	}
+
+	return "membership unlocked", nil
+}
+
+func (r *PlaytestReconciler) startNow(_ context.Context, playtest *gamev1alpha1.Playtest, _ string, now time.Time) (string, error) {
+	if !now.Before(playtest.Spec.StartTime.Time) {
+		return "", fmt.Errorf("playtest already started at %s", playtest.Spec.StartTime.UTC().Format(time.RFC3339))
+	}
+
+	playtest.Spec.StartTime = metav1.NewTime(now)
+
+	return fmt.Sprintf("start time moved up to %s", now.UTC().Format(time.RFC3339)), nil
+}
+
+func (r *PlaytestReconciler) extendEnd(_ context.Context, playtest *gamev1alpha1.Playtest, value string, _ time.Time) (string, error) {
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes <= 0 {
+		return "", fmt.Errorf("%q is not a positive number of minutes", value)
+	}
+
+	extendBy, _ := strconv.Atoi(playtest.GetAnnotations()[ExtendByAnnotation])
+	if playtest.Annotations == nil {
+		playtest.Annotations = map[string]string{}
+	}
+	playtest.Annotations[ExtendByAnnotation] = strconv.Itoa(extendBy + minutes)
+
+	return fmt.Sprintf("end time extended to %s", playtestEndTime(playtest).UTC().Format(time.RFC3339)), nil
+}
+
+func (r *PlaytestReconciler) reshuffle(_ context.Context, playtest *gamev1alpha1.Playtest, _ string, _ time.Time) (string, error) {
+	if playtest.Spec.LockMembership {
+		return "", fmt.Errorf("membership is locked")
+	}
+
+	parties := userParties(playtest)
+
+	users := []string{}
+	queued := map[string]int{}
+	partyQueue := []gamev1alpha1.PlaytestParty{}
+	for i := range playtest.Spec.Groups {
+		for _, user := range playtest.Spec.Groups[i].Users {
+			party, ok := parties[user]
+			if !ok {
+				users = append(users, user)
+				continue
+			}
+
+			j, ok := queued[party]
+			if !ok {
+				j = len(partyQueue)
+				queued[party] = j
+				partyQueue = append(partyQueue, gamev1alpha1.PlaytestParty{Name: party})
+			}
+			partyQueue[j].Users = append(partyQueue[j].Users, user)
+		}
+
+		playtest.Spec.Groups[i].Users = nil
+	}
+
+	rand.Shuffle(len(users), func(i, j int) { users[i], users[j] = users[j], users[i] })
+	rand.Shuffle(len(partyQueue), func(i, j int) { partyQueue[i], partyQueue[j] = partyQueue[j], partyQueue[i] })
+
+	playtest.Spec.PartiesToAutoAssign = append(partyQueue, playtest.Spec.PartiesToAutoAssign...)
+	playtest.Spec.UsersToAutoAssign = append(users, playtest.Spec.UsersToAutoAssign...)
+
+	return fmt.Sprintf("requeued %d users and %d parties", len(users), len(partyQueue)), nil
+}
+
+func (r *PlaytestReconciler) rebalance(_ context.Context, playtest *gamev1alpha1.Playtest, _ string, now time.Time) (string, error) {
+	if playtest.Spec.LockMembership {
+		return "", fmt.Errorf("membership is locked")
+	}
+
+	moves := rebalanceGroups(playtest)
+	playtest.Status.LastRebalance = &gamev1alpha1.PlaytestRebalance{
+		Time:  metav1.NewTime(now),
+		Moves: moves,
+	}
+
+	return fmt.Sprintf("moved %d users", len(moves)), nil
+}
+
+func (r *PlaytestReconciler) restartGroup(ctx context.Context, playtest *gamev1alpha1.Playtest, value string, now time.Time) (string, error) {
+	groupStatus := getGroupStatus(playtest, value)
+	if getGroup(playtest, value) == nil || groupStatus == nil {
+		return "", fmt.Errorf("no group named %q", value)
+	}
+
+	if groupStatus.ServerRef == nil {
+		return "", fmt.Errorf("group %q has no server", value)
+	}
+
+	gameServer := &gamev1alpha1.GameServer{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: playtest.GetNamespace(), Name: groupStatus.ServerRef.Name}, gameServer); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("group %q has no server", value)
+		}
+
+		return "", err
+	}
+
+	before := gameServer.DeepCopy()
+	if gameServer.Annotations == nil {
+		gameServer.Annotations = map[string]string{}
+	}
+	gameServer.Annotations[RestartAnnotation] = now.UTC().Format(time.RFC3339)
+	if err := r.Client.Patch(ctx, gameServer, client.MergeFrom(before)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("restarting server %s", gameServer.GetName()), nil
+}
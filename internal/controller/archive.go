@@ -0,0 +1,142 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+const (
+	// ArchiveFileName is the key of the archive record in a playtest's archive ConfigMap
+	ArchiveFileName = "playtest.json"
+
+	// ArchiveLabel marks ConfigMaps holding playtest archive records
+	ArchiveLabel = "believer.dev/playtest-archive"
+)
+
+// playtestArchive is the record kept of a playtest after it has been pruned
+type playtestArchive struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	Version     string `json:"version"`
+	Map         string `json:"map"`
+
+	StartTime metav1.Time `json:"startTime"`
+	EndTime   metav1.Time `json:"endTime"`
+	PrunedAt  metav1.Time `json:"prunedAt"`
+
+	Phase            gamev1alpha1.PlaytestPhase             `json:"phase,omitempty"`
+	PhaseTransitions []gamev1alpha1.PlaytestPhaseTransition `json:"phaseTransitions,omitempty"`
+
+	Groups []playtestArchiveGroup `json:"groups"`
+}
+
+type playtestArchiveGroup struct {
+	Name    string   `json:"name"`
+	Users   []string `json:"users"`
+	Version string   `json:"version"`
+	Map     string   `json:"map"`
+	Server  string   `json:"server,omitempty"`
+	Ready   bool     `json:"ready"`
+	Reason  string   `json:"reason,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+func newPlaytestArchive(playtest *gamev1alpha1.Playtest, now time.Time) *playtestArchive {
+	archive := &playtestArchive{
+		Name:             playtest.GetName(),
+		DisplayName:      playtest.Spec.DisplayName,
+		Version:          playtest.Spec.Version,
+		Map:              playtest.Spec.Map,
+		StartTime:        playtest.Spec.StartTime,
+		EndTime:          metav1.NewTime(playtestEndTime(playtest)),
+		PrunedAt:         metav1.NewTime(now),
+		Phase:            playtest.Status.Phase,
+		PhaseTransitions: playtest.Status.PhaseTransitions,
+		Groups:           []playtestArchiveGroup{},
+	}
+
+	for _, group := range playtest.Spec.Groups {
+		settings := resolveGroupServer(playtest, group)
+		archiveGroup := playtestArchiveGroup{
+			Name:    group.Name,
+			Users:   append([]string{}, group.Users...),
+			Version: settings.Version,
+			Map:     settings.Map,
+		}
+
+		// servers are long gone by the time a playtest is pruned, so prefer how they were at the end
+		if groupStatus := getGroupStatus(playtest, group.Name); groupStatus != nil && groupStatus.Outcome != nil {
+			archiveGroup.Server = groupStatus.Outcome.Server
+			archiveGroup.Ready = groupStatus.Outcome.Ready
+			archiveGroup.Reason = groupStatus.Outcome.Reason
+			archiveGroup.Message = groupStatus.Outcome.Message
+		} else if groupStatus != nil {
+			if groupStatus.ServerRef != nil {
+				archiveGroup.Server = groupStatus.ServerRef.Name
+			}
+			archiveGroup.Ready = groupStatus.Ready
+			archiveGroup.Reason = groupStatus.Reason
+			archiveGroup.Message = groupStatus.Message
+		}
+
+		archive.Groups = append(archive.Groups, archiveGroup)
+	}
+
+	return archive
+}
+
+// archivePlaytest writes a record of the playtest to a ConfigMap that outlives it. The ConfigMap is
+// deliberately not owned by the Playtest so it isn't garbage collected along with it.
+func archivePlaytest(ctx context.Context, c client.Client, playtest *gamev1alpha1.Playtest) error {
+	record, err := json.MarshalIndent(newPlaytestArchive(playtest, time.Now()), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      playtest.GetName() + "-archive",
+			Namespace: playtest.GetNamespace(),
+		},
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, c, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels[ArchiveLabel] = "true"
+		configMap.Labels[PlaytestLabel] = playtest.GetName()
+
+		configMap.Data = map[string]string{
+			ArchiveFileName: string(record),
+		}
+
+		return nil
+	})
+
+	return err
+}
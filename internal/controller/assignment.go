@@ -0,0 +1,443 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// GroupAssigner picks the group users are auto-assigned to
+type GroupAssigner interface {
+	// Assign returns the index into playtest.Spec.Groups of the group the users should join together.
+	// users is a single user or a party. openGroups holds the indices of the groups with room for all
+	// of them, and is never empty.
+	Assign(playtest *gamev1alpha1.Playtest, users []string, openGroups []int) int
+}
+
+// builtinAssigners are the assignment strategies every operator supports
+var builtinAssigners = map[gamev1alpha1.AssignmentStrategy]GroupAssigner{
+	gamev1alpha1.AssignmentStrategyRandom:      RandomAssigner{},
+	gamev1alpha1.AssignmentStrategyLeastFilled: LeastFilledAssigner{},
+	gamev1alpha1.AssignmentStrategyRoundRobin:  RoundRobinAssigner{},
+	gamev1alpha1.AssignmentStrategySeeded:      SeededAssigner{},
+}
+
+// RandomAssigner picks any open group
+type RandomAssigner struct{}
+
+func (RandomAssigner) Assign(_ *gamev1alpha1.Playtest, _ []string, openGroups []int) int {
+	return openGroups[rand.Intn(len(openGroups))]
+}
+
+// LeastFilledAssigner picks the open group with the fewest users, preferring earlier groups on ties
+type LeastFilledAssigner struct{}
+
+func (LeastFilledAssigner) Assign(playtest *gamev1alpha1.Playtest, _ []string, openGroups []int) int {
+	best := openGroups[0]
+	for _, i := range openGroups[1:] {
+		if len(playtest.Spec.Groups[i].Users) < len(playtest.Spec.Groups[best].Users) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// RoundRobinAssigner picks the next open group after the one the previous users were assigned to
+type RoundRobinAssigner struct{}
+
+func (RoundRobinAssigner) Assign(playtest *gamev1alpha1.Playtest, _ []string, openGroups []int) int {
+	last := -1
+	if n := len(playtest.Status.Assignments); n > 0 {
+		for i, group := range playtest.Spec.Groups {
+			if group.Name == playtest.Status.Assignments[n-1].Group {
+				last = i
+				break
+			}
+		}
+	}
+
+	for _, i := range openGroups {
+		if i > last {
+			return i
+		}
+	}
+
+	return openGroups[0]
+}
+
+// SeededAssigner picks an open group by hashing the users with the playtest's assignment seed, so the
+// same queue against the same groups always produces the same assignments
+type SeededAssigner struct{}
+
+func (SeededAssigner) Assign(playtest *gamev1alpha1.Playtest, users []string, openGroups []int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strconv.FormatInt(playtest.Spec.AssignmentSeed, 10)))
+	_, _ = h.Write([]byte(strings.Join(users, ",")))
+
+	return openGroups[int(h.Sum32()%uint32(len(openGroups)))]
+}
+
+// groupAssigner returns the assigner for the playtest's strategy, preferring ones registered on the reconciler
+func (r *PlaytestReconciler) groupAssigner(playtest *gamev1alpha1.Playtest) (gamev1alpha1.AssignmentStrategy, GroupAssigner, bool) {
+	strategy := playtest.Spec.AssignmentStrategy
+	if strategy == "" {
+		strategy = gamev1alpha1.AssignmentStrategyRandom
+	}
+
+	if assigner, ok := r.Assigners[strategy]; ok {
+		return strategy, assigner, true
+	}
+
+	assigner, ok := builtinAssigners[strategy]
+	return strategy, assigner, ok
+}
+
+// recordAssignment notes in the playtest's status which group a user was assigned to and how,
+// replacing any earlier assignment of the same user. party is empty for users assigned on their own.
+func recordAssignment(playtest *gamev1alpha1.Playtest, user, party, group string, strategy gamev1alpha1.AssignmentStrategy, now time.Time) {
+	assignments := []gamev1alpha1.PlaytestAssignment{}
+	for _, assignment := range playtest.Status.Assignments {
+		if assignment.User != user {
+			assignments = append(assignments, assignment)
+		}
+	}
+
+	playtest.Status.Assignments = append(assignments, gamev1alpha1.PlaytestAssignment{
+		User:     user,
+		Party:    party,
+		Group:    group,
+		Strategy: strategy,
+		Time:     metav1.NewTime(now),
+	})
+}
+
+// assignQueued places everyone waitlisted, then every queued party and user, against the same view of
+// the groups. Anyone who doesn't fit is waitlisted so they don't hold up the rest of the queue.
+func (r *PlaytestReconciler) assignQueued(ctx context.Context, playtest *gamev1alpha1.Playtest) {
+	log := log.FromContext(ctx)
+
+	if len(playtest.Status.Waitlist) == 0 && len(playtest.Spec.PartiesToAutoAssign) == 0 && len(playtest.Spec.UsersToAutoAssign) == 0 {
+		return
+	}
+
+	strategy, assigner, ok := r.groupAssigner(playtest)
+	if !ok {
+		log.Error(fmt.Errorf("unknown assignment strategy %q", strategy), "falling back to random assignment")
+
+		strategy, assigner = gamev1alpha1.AssignmentStrategyRandom, RandomAssigner{}
+	}
+
+	now := time.Now()
+
+	// waitlisted users get first pick of any room that's opened up since
+	waitlist := playtest.Status.Waitlist
+	playtest.Status.Waitlist = nil
+	for _, entry := range waitlist {
+		if entry.Users = unplaced(playtest, entry.Users); len(entry.Users) == 0 {
+			continue
+		}
+
+		if err := assignUsers(playtest, entry.Party, entry.Users, strategy, assigner); err != nil {
+			entry.Reason = err.Error()
+			playtest.Status.Waitlist = append(playtest.Status.Waitlist, entry)
+			continue
+		}
+
+		log.Info("assigned waitlisted users", "users", entry.Users, "party", entry.Party, "strategy", strategy)
+	}
+
+	// parties go before single users, since they're the hardest to fit
+	for _, party := range playtest.Spec.PartiesToAutoAssign {
+		if party.Users = unplaced(playtest, party.Users); len(party.Users) == 0 {
+			continue
+		}
+
+		if err := assignUsers(playtest, party.Name, party.Users, strategy, assigner); err != nil {
+			log.Info("waitlisting party", "party", party.Name, "reason", err.Error())
+
+			addToWaitlist(playtest, party.Name, party.Users, err.Error(), now)
+			continue
+		}
+
+		log.Info("assigned party", "party", party.Name, "strategy", strategy)
+	}
+	playtest.Spec.PartiesToAutoAssign = nil
+
+	for _, user := range playtest.Spec.UsersToAutoAssign {
+		if len(unplaced(playtest, []string{user})) == 0 {
+			continue
+		}
+
+		if err := assignUsers(playtest, "", []string{user}, strategy, assigner); err != nil {
+			log.Info("waitlisting user", "user", user, "reason", err.Error())
+
+			addToWaitlist(playtest, "", []string{user}, err.Error(), now)
+			continue
+		}
+
+		log.Info("assigned user", "user", user, "strategy", strategy)
+	}
+	playtest.Spec.UsersToAutoAssign = nil
+}
+
+// unplaced returns the users who aren't in a group or on the waitlist yet. Users can still be queued after
+// they've been placed if the status recording it was saved but taking them off the queue wasn't.
+func unplaced(playtest *gamev1alpha1.Playtest, users []string) []string {
+	placed := map[string]bool{}
+	for _, group := range playtest.Spec.Groups {
+		for _, user := range group.Users {
+			placed[user] = true
+		}
+	}
+	for _, entry := range playtest.Status.Waitlist {
+		for _, user := range entry.Users {
+			placed[user] = true
+		}
+	}
+
+	remaining := []string{}
+	for _, user := range users {
+		if !placed[user] {
+			remaining = append(remaining, user)
+		}
+	}
+
+	return remaining
+}
+
+// assignUsers places users, a single user or a party, into a group together. If no group has room for
+// all of them a group is added, and only if that isn't possible is the party split across the groups
+// with the most room. It returns an error describing why the users couldn't be placed.
+func assignUsers(playtest *gamev1alpha1.Playtest, party string, users []string, strategy gamev1alpha1.AssignmentStrategy, assigner GroupAssigner) error {
+	now := time.Now()
+	started := !now.Before(playtest.Spec.StartTime.Time)
+
+	openGroups := []int{}
+	for i := range playtest.Spec.Groups {
+		if groupRoom(playtest, i, started) >= len(users) {
+			openGroups = append(openGroups, i)
+		}
+	}
+
+	// late joiners go to servers that take them, if any do
+	if started {
+		joinable := []int{}
+		for _, i := range openGroups {
+			if acceptsJoiners(playtest, playtest.Spec.Groups[i].Name) {
+				joinable = append(joinable, i)
+			}
+		}
+
+		if len(joinable) > 0 {
+			openGroups = joinable
+		}
+	}
+
+	if len(openGroups) == 0 && len(users) <= playtest.Spec.PlayersPerGroup && len(playtest.Spec.Groups) < maxGroups(playtest) {
+		addGroup(playtest)
+		openGroups = append(openGroups, len(playtest.Spec.Groups)-1)
+	}
+
+	if len(openGroups) > 0 {
+		groupIndex := assigner.Assign(playtest, users, openGroups)
+
+		valid := false
+		for _, i := range openGroups {
+			valid = valid || i == groupIndex
+		}
+		if !valid {
+			return fmt.Errorf("assignment strategy %q picked group %d, which has no room", strategy, groupIndex)
+		}
+
+		group := &playtest.Spec.Groups[groupIndex]
+		group.Users = append(group.Users, users...)
+		for _, user := range users {
+			recordAssignment(playtest, user, party, group.Name, strategy, now)
+			if started {
+				recordLateJoiner(playtest, user, group.Name, now)
+			}
+		}
+
+		return nil
+	}
+
+	if len(users) == 1 {
+		return fmt.Errorf("no open groups")
+	}
+
+	// split the party as a last resort, keeping as many of them together as we can
+	open := 0
+	for i := range playtest.Spec.Groups {
+		if room := groupRoom(playtest, i, started); room > 0 {
+			open += room
+		}
+	}
+	if open < len(users) {
+		return fmt.Errorf("party of %d needs more room than the %d open slots", len(users), open)
+	}
+
+	byRoom := []int{}
+	for i := range playtest.Spec.Groups {
+		byRoom = append(byRoom, i)
+	}
+	sort.SliceStable(byRoom, func(a, b int) bool {
+		return groupRoom(playtest, byRoom[a], started) > groupRoom(playtest, byRoom[b], started)
+	})
+
+	remaining := users
+	for _, i := range byRoom {
+		group := &playtest.Spec.Groups[i]
+
+		n := groupRoom(playtest, i, started)
+		if n <= 0 {
+			continue
+		}
+		if n > len(remaining) {
+			n = len(remaining)
+		}
+
+		group.Users = append(group.Users, remaining[:n]...)
+		for _, user := range remaining[:n] {
+			recordAssignment(playtest, user, party, group.Name, strategy, now)
+			if started {
+				recordLateJoiner(playtest, user, group.Name, now)
+			}
+		}
+
+		if remaining = remaining[n:]; len(remaining) == 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// groupRoom returns how many more users fit in the group at index i. Once the playtest has started, the
+// players its server reports as connected count too, in case more have joined than were assigned.
+func groupRoom(playtest *gamev1alpha1.Playtest, i int, started bool) int {
+	group := playtest.Spec.Groups[i]
+
+	used := len(group.Users)
+	if groupStatus := getGroupStatus(playtest, group.Name); started && groupStatus != nil && groupStatus.Players != nil {
+		if players := int(*groupStatus.Players); players > used {
+			used = players
+		}
+	}
+
+	return playtest.Spec.PlayersPerGroup - used
+}
+
+// acceptsJoiners returns false if the group's server reports it isn't taking players mid-session
+func acceptsJoiners(playtest *gamev1alpha1.Playtest, groupName string) bool {
+	groupStatus := getGroupStatus(playtest, groupName)
+
+	return groupStatus == nil || groupStatus.AcceptingJoiners == nil || *groupStatus.AcceptingJoiners
+}
+
+// recordLateJoiner notes a user assigned after the playtest started, replacing any earlier entry for
+// them. Their connect info is filled in by updateLateJoiners.
+func recordLateJoiner(playtest *gamev1alpha1.Playtest, user, group string, now time.Time) {
+	lateJoiners := []gamev1alpha1.PlaytestLateJoiner{}
+	for _, lateJoiner := range playtest.Status.LateJoiners {
+		if lateJoiner.User != user {
+			lateJoiners = append(lateJoiners, lateJoiner)
+		}
+	}
+
+	playtest.Status.LateJoiners = append(lateJoiners, gamev1alpha1.PlaytestLateJoiner{
+		User:  user,
+		Group: group,
+		Time:  metav1.NewTime(now),
+	})
+}
+
+// updateLateJoiners keeps each late joiner's group and connect info current, dropping users who are no
+// longer in a group
+func updateLateJoiners(playtest *gamev1alpha1.Playtest) {
+	userGroups := map[string]string{}
+	for _, group := range playtest.Spec.Groups {
+		for _, user := range group.Users {
+			userGroups[user] = group.Name
+		}
+	}
+
+	lateJoiners := []gamev1alpha1.PlaytestLateJoiner{}
+	for _, lateJoiner := range playtest.Status.LateJoiners {
+		group, ok := userGroups[lateJoiner.User]
+		if !ok {
+			continue
+		}
+
+		lateJoiner.Group = group
+		lateJoiner.Server = ""
+		lateJoiner.Address = ""
+		if groupStatus := getGroupStatus(playtest, group); groupStatus != nil && groupStatus.ServerRef != nil {
+			lateJoiner.Server = groupStatus.ServerRef.Name
+			lateJoiner.Address = groupStatus.Address
+		}
+
+		lateJoiners = append(lateJoiners, lateJoiner)
+	}
+	playtest.Status.LateJoiners = lateJoiners
+}
+
+// addToWaitlist parks users that couldn't be assigned in the playtest's waitlist
+func addToWaitlist(playtest *gamev1alpha1.Playtest, party string, users []string, reason string, now time.Time) {
+	playtest.Status.Waitlist = append(playtest.Status.Waitlist, gamev1alpha1.PlaytestWaitlistEntry{
+		Users:  users,
+		Reason: reason,
+		Since:  metav1.NewTime(now),
+		Party:  party,
+	})
+}
+
+// pendingWaitlist returns the waitlist to save before the groups are: everyone on the new waitlist, plus
+// anyone who left the old one for a group, in case their group isn't saved. They're dropped from it once
+// they're found in a group.
+func pendingWaitlist(before, after []gamev1alpha1.PlaytestWaitlistEntry) []gamev1alpha1.PlaytestWaitlistEntry {
+	listed := map[string]bool{}
+	for _, entry := range after {
+		for _, user := range entry.Users {
+			listed[user] = true
+		}
+	}
+
+	waitlist := append([]gamev1alpha1.PlaytestWaitlistEntry{}, after...)
+	for _, entry := range before {
+		for _, user := range entry.Users {
+			if !listed[user] {
+				waitlist = append(waitlist, entry)
+				break
+			}
+		}
+	}
+
+	return waitlist
+}
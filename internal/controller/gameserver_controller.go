@@ -19,9 +19,10 @@ package controller
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
+	"net/http"
 	"strings"
 	"time"
 
@@ -31,10 +32,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
@@ -42,8 +47,37 @@ import (
 
 const (
 	ErrPortConflict = "node(s) didn't have free ports for the requested pod ports"
+
+	// DrainingAnnotation marks a GameServer that should stop accepting players ahead of being deleted. Its value
+	// is the RFC 3339 time draining started, and is exposed to the game server in /var/run/fellowship/draining.
+	DrainingAnnotation = "believer.dev/draining"
+
+	// AllowlistFileName is the file in /var/run/fellowship listing the users allowed on a playtest server
+	AllowlistFileName = "allowlist.json"
+
+	// basePendingTimeoutBackoff is how long to wait before recreating a Pod that exceeded its PendingTimeout for
+	// the first time in a row. Each further timeout doubles it, up to maxPendingTimeoutBackoff.
+	basePendingTimeoutBackoff = 1 * time.Minute
+	maxPendingTimeoutBackoff  = 15 * time.Minute
 )
 
+// imagePullFailureReasons are the container waiting reasons the kubelet reports when it can't pull an image
+var imagePullFailureReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// allowlist is the format of the allowlist file mounted into playtest game servers
+type allowlist struct {
+	Users []allowlistEntry `json:"users"`
+}
+
+type allowlistEntry struct {
+	Name string `json:"name"`
+}
+
 // GameServerReconciler reconciles a GameServer object
 type GameServerReconciler struct {
 	client.Client
@@ -54,6 +88,23 @@ type GameServerReconciler struct {
 	GamePortMax     int32
 	NetImguiPortMin int32
 	StatusPortMin   int32
+
+	// MaxRescheduleAttempts is the number of consecutive port conflicts after which a GameServer is
+	// marked unschedulable. Zero means retry forever.
+	MaxRescheduleAttempts int32
+	// RescheduleBackoff is the delay before the first reschedule, doubled on every further attempt
+	RescheduleBackoff time.Duration
+	// MaxRescheduleBackoff caps the delay between reschedule attempts
+	MaxRescheduleBackoff time.Duration
+
+	// Recorder records Events on game servers, such as restarts
+	Recorder record.EventRecorder
+
+	// SessionStatusInterval is how often ready game servers are asked for their player count. Zero
+	// disables polling.
+	SessionStatusInterval time.Duration
+	// HTTPClient is used to poll game servers. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
 }
 
 //+kubebuilder:rbac:groups=game.believer.dev,resources=gameservers,verbs=get;list;watch;create;update;patch;delete
@@ -61,6 +112,8 @@ type GameServerReconciler struct {
 //+kubebuilder:rbac:groups=game.believer.dev,resources=gameservers/finalizers,verbs=update
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -77,6 +130,9 @@ func (r *GameServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// At this point the Reconcile function has been handed a name and a namespace. Now we need to fetch the object.
 	gameServer := &gamev1alpha1.GameServer{}
 	if err := r.Client.Get(ctx, req.NamespacedName, gameServer); err != nil {
+		if apierrors.IsNotFound(err) {
+			rescheduleAttempts.DeleteLabelValues(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
@@ -101,7 +157,117 @@ func (r *GameServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		gameServer.Spec.DisplayName = gameServer.GetName()
 	}
 
-	return r.reconcilePod(ctx, gameServer)
+	// the allowlist has to exist before the Pod that mounts it
+	if err := r.reconcileAllowlist(ctx, gameServer); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// restart on request by deleting the Pod, a fresh one is created on the next pass
+	if _, ok := gameServer.GetAnnotations()[RestartAnnotation]; ok {
+		return r.restartPod(ctx, gameServer)
+	}
+
+	result, err := r.reconcilePod(ctx, gameServer)
+	if err != nil || r.SessionStatusInterval <= 0 || !gameServer.Status.Ready {
+		return result, err
+	}
+
+	// player counts change without anything in the cluster changing, so poll for them
+	next := r.pollSessionStatus(ctx, gameServer)
+	if !result.Requeue && (result.RequeueAfter == 0 || result.RequeueAfter > next) {
+		result.RequeueAfter = next
+	}
+
+	return result, nil
+}
+
+// restartPod deletes the GameServer's Pod and removes the restart annotation.
+// It also gives a GameServer that ran out of reschedule attempts a fresh set.
+func (r *GameServerReconciler) restartPod(ctx context.Context, gameServer *gamev1alpha1.GameServer) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	delete(gameServer.Annotations, RestartAnnotation)
+
+	if gameServer.Status.RescheduleAttempts != 0 {
+		gameServer.Status.RescheduleAttempts = 0
+		gameServer.Status.LastPortConflict = nil
+		rescheduleAttempts.WithLabelValues(gameServer.GetNamespace(), gameServer.GetName()).Set(0)
+	}
+	if conditions.GetReason(gameServer, gamev1alpha1.PodScheduledCondition) == gamev1alpha1.RescheduleAttemptsExceededReason {
+		conditions.Delete(gameServer, gamev1alpha1.PodScheduledCondition)
+	}
+
+	if gameServer.Status.PodRef == nil {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	log.Info("restarting pod", "pod", gameServer.Status.PodRef.Name)
+
+	pod := &corev1.Pod{}
+	pod.SetName(gameServer.Status.PodRef.Name)
+	pod.SetNamespace(gameServer.GetNamespace())
+	if err := r.Client.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+
+	gameServer.Status.PodRef = nil
+	gameServer.Status.Ready = false
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(gameServer, corev1.EventTypeNormal, RestartedReason, "Restarted pod %s", pod.GetName())
+	}
+
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// reconcileAllowlist keeps the allowlist ConfigMap of a playtest game server in sync with its group's users.
+// Mounted ConfigMaps are updated in place by the kubelet, so the server sees group changes mid-playtest.
+func (r *GameServerReconciler) reconcileAllowlist(ctx context.Context, gameServer *gamev1alpha1.GameServer) error {
+	if gameServer.Spec.Playtest == nil {
+		return nil
+	}
+
+	list := allowlist{
+		Users: []allowlistEntry{},
+	}
+	for _, user := range gameServer.Spec.Playtest.Users {
+		list.Users = append(list.Users, allowlistEntry{Name: user})
+	}
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      allowlistConfigMapName(gameServer),
+			Namespace: gameServer.GetNamespace(),
+		},
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.OwnerReferences = []metav1.OwnerReference{
+			{
+				APIVersion: gamev1alpha1.GroupVersion.String(),
+				Kind:       "GameServer",
+				Name:       gameServer.GetName(),
+				UID:        gameServer.GetUID(),
+				Controller: pointer.Bool(true),
+			},
+		}
+		configMap.Data = map[string]string{
+			AllowlistFileName: string(data),
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func allowlistConfigMapName(gameServer *gamev1alpha1.GameServer) string {
+	return fmt.Sprintf("%s-allowlist", gameServer.GetName())
 }
 
 func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gamev1alpha1.GameServer) (ctrl.Result, error) {
@@ -132,21 +298,77 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 
 		gameServer.Status.PodStatus = &pod.Status
 
+		// let the game server know it should wind down
+		if draining, ok := gameServer.GetAnnotations()[DrainingAnnotation]; ok && pod.Annotations[DrainingAnnotation] != draining {
+			if pod.Annotations == nil {
+				pod.Annotations = make(map[string]string)
+			}
+
+			pod.Annotations[DrainingAnnotation] = draining
+		}
+
 		// check Pod conditions
 		switch pod.Status.Phase {
 		case corev1.PodPending:
-			// if unschedulable because of port conflict, delete the Pod and requeue
+			// if unschedulable because of port conflict, delete the Pod and requeue. Any other
+			// scheduling failure is surfaced as a condition and left to the scheduler to retry.
 			for _, condition := range pod.Status.Conditions {
-				if condition.Type == corev1.PodScheduled && condition.Reason == corev1.PodReasonUnschedulable && strings.Contains(condition.Message, ErrPortConflict) {
-					log.Info("port conflict detected, rescheduling pod")
+				if condition.Type != corev1.PodScheduled || condition.Reason != corev1.PodReasonUnschedulable {
+					continue
+				}
+
+				if strings.Contains(condition.Message, ErrPortConflict) {
+					log.Info("port conflict detected, rescheduling pod", "attempt", gameServer.Status.RescheduleAttempts+1)
 					if err := r.Client.Delete(ctx, pod); err != nil {
 						return ctrl.Result{}, err
 					}
 
+					now := metav1.Now()
 					gameServer.Status.PodRef = nil
+					gameServer.Status.RescheduleAttempts++
+					gameServer.Status.LastPortConflict = &now
 
-					return ctrl.Result{Requeue: true}, nil
+					portConflictsTotal.WithLabelValues(gameServer.GetNamespace()).Inc()
+					rescheduleAttempts.WithLabelValues(gameServer.GetNamespace(), gameServer.GetName()).Set(float64(gameServer.Status.RescheduleAttempts))
+
+					if r.MaxRescheduleAttempts > 0 && gameServer.Status.RescheduleAttempts >= r.MaxRescheduleAttempts {
+						log.Info("giving up on rescheduling pod", "attempts", gameServer.Status.RescheduleAttempts)
+						rescheduleExhaustedTotal.WithLabelValues(gameServer.GetNamespace()).Inc()
+						conditions.MarkFalse(gameServer, gamev1alpha1.PodScheduledCondition, gamev1alpha1.RescheduleAttemptsExceededReason, clusterv1.ConditionSeverityError,
+							"gave up after %d consecutive port conflicts", gameServer.Status.RescheduleAttempts)
+
+						return ctrl.Result{}, nil
+					}
+
+					conditions.MarkFalse(gameServer, gamev1alpha1.PodScheduledCondition, gamev1alpha1.PortConflictReason, clusterv1.ConditionSeverityInfo, "%s", condition.Message)
+
+					return ctrl.Result{RequeueAfter: r.rescheduleBackoff(gameServer.Status.RescheduleAttempts)}, nil
 				}
+
+				conditions.MarkFalse(gameServer, gamev1alpha1.PodScheduledCondition, gamev1alpha1.PodUnschedulableReason, clusterv1.ConditionSeverityWarning, "%s", condition.Message)
+			}
+
+			if reason, message := imagePullFailure(pod); reason != "" {
+				conditions.MarkFalse(gameServer, gamev1alpha1.ImagePulledCondition, reason, clusterv1.ConditionSeverityError, "%s", message)
+			}
+
+			// if the Pod has been stuck for too long, delete it and back off before trying again
+			if timeout := gameServer.Spec.PendingTimeout; timeout != nil && time.Since(pod.CreationTimestamp.Time) > timeout.Duration {
+				log.Info("pod pending timeout exceeded, deleting pod", "timeout", timeout.Duration)
+				if err := r.Client.Delete(ctx, pod); err != nil && !apierrors.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+
+				now := metav1.Now()
+				gameServer.Status.PodRef = nil
+				gameServer.Status.PendingTimeouts++
+				gameServer.Status.LastPendingTimeout = &now
+
+				backoff := pendingTimeoutBackoff(gameServer.Status.PendingTimeouts)
+				conditions.MarkFalse(gameServer, gamev1alpha1.PodScheduledCondition, gamev1alpha1.PendingTimeoutReason, clusterv1.ConditionSeverityWarning,
+					"pod was pending for longer than %s, retrying in %s", timeout.Duration, backoff)
+
+				return ctrl.Result{RequeueAfter: backoff}, nil
 			}
 		case corev1.PodSucceeded:
 			// if the pod has exited successfully, we should delete the GameServer object
@@ -162,32 +384,50 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 			return ctrl.Result{}, nil
 		}
 
+		// a Pod that got past Pending ends the current run of pending timeouts
+		if pod.Status.Phase != corev1.PodPending {
+			gameServer.Status.PendingTimeouts = 0
+		}
+
 		if len(pod.Spec.Containers[0].Ports) == 0 {
 			return ctrl.Result{Requeue: true}, nil
 		}
 
 		// iterate over the ports
+		ports := map[string]int32{}
 		for _, port := range pod.Spec.Containers[0].Ports {
-			if port.Name == "game" {
-				if gameServer.Status.Port != port.ContainerPort {
-					gameServer.Status.Port = port.ContainerPort
-				}
-			} else if port.Name == "netimgui" {
-				if gameServer.Status.NetImguiPort != port.ContainerPort {
-					gameServer.Status.NetImguiPort = port.ContainerPort
-				}
-			} else if port.Name == "status" {
-				if gameServer.Status.StatusPort != port.ContainerPort {
-					gameServer.Status.StatusPort = port.ContainerPort
-				}
+			ports[port.Name] = port.ContainerPort
+
+			switch port.Name {
+			case GamePortName:
+				gameServer.Status.Port = port.ContainerPort
+			case NetImguiPortName:
+				gameServer.Status.NetImguiPort = port.ContainerPort
+			case StatusPortName:
+				gameServer.Status.StatusPort = port.ContainerPort
 			}
 		}
+		gameServer.Status.Ports = ports
 
 		// requeue until we've got a node
 		if pod.Spec.NodeName == "" {
 			return ctrl.Result{Requeue: true}, nil
 		}
 
+		conditions.MarkTrue(gameServer, gamev1alpha1.PodScheduledCondition)
+
+		// a scheduled Pod ends the current run of port conflicts
+		if gameServer.Status.RescheduleAttempts != 0 {
+			gameServer.Status.RescheduleAttempts = 0
+			rescheduleAttempts.WithLabelValues(gameServer.GetNamespace(), gameServer.GetName()).Set(0)
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.ImageID != "" {
+				conditions.MarkTrue(gameServer, gamev1alpha1.ImagePulledCondition)
+			}
+		}
+
 		ip, err := r.getExternalIPForNode(ctx, pod.Spec.NodeName)
 		if err != nil {
 			return ctrl.Result{}, err
@@ -230,8 +470,31 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 		return ctrl.Result{}, nil
 	}
 
+	// once we've run out of reschedule attempts, stop creating Pods until the GameServer is restarted
+	if r.MaxRescheduleAttempts > 0 && gameServer.Status.RescheduleAttempts >= r.MaxRescheduleAttempts {
+		return ctrl.Result{}, nil
+	}
+
+	// back off between port conflicts so a nearly full port range doesn't turn into a create/delete loop
+	if last := gameServer.Status.LastPortConflict; last != nil && gameServer.Status.RescheduleAttempts > 0 {
+		if wait := time.Until(last.Add(r.rescheduleBackoff(gameServer.Status.RescheduleAttempts))); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	// if we recently gave up on a Pod that was stuck pending, wait before trying again
+	if last := gameServer.Status.LastPendingTimeout; last != nil && gameServer.Status.PendingTimeouts > 0 {
+		if wait := time.Until(last.Add(pendingTimeoutBackoff(gameServer.Status.PendingTimeouts))); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
 	image := fmt.Sprintf("%s:%s", r.GameServerImage, gameServer.Spec.Version)
 
+	// Select ports randomly from our ranges
+	ports := r.allocatePorts(ctx, gameServer)
+	templateData := newGameServerTemplateData(gameServer, ports)
+
 	args := []string{}
 
 	// map needs to be the first argument
@@ -239,24 +502,42 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 		args = append(args, gameServer.Spec.Map)
 	}
 
-	args = append(args, gameServer.Spec.CmdArgs...)
+	for _, arg := range gameServer.Spec.CmdArgs {
+		rendered, err := renderTemplate(arg, templateData)
+		if err != nil {
+			// retrying won't help, so wait for the spec to be fixed
+			conditions.MarkFalse(gameServer, gamev1alpha1.TemplatesRenderedCondition, gamev1alpha1.InvalidTemplateReason, clusterv1.ConditionSeverityError,
+				"failed to expand cmdArg %q: %s", arg, err.Error())
+			return ctrl.Result{}, nil
+		}
+
+		args = append(args, rendered)
+	}
 
-	// Select port randomly from our range
-	// We do not need to keep track of which ports we've assigned. This controller watches
-	// Pods that are children of GameServers, and can detect when one is unschedulable due to a port
-	// conflict, then delete the Pod. Yes, as we start to run out of overall ports there will be some
-	// thrashing, but doing it this way allows us to scale out nodes without needing to maintain a
-	// mapping of nodes and ports.
-	port := rand.Int31n(r.GamePortMax-r.GamePortMin) + r.GamePortMin
-	portArg := fmt.Sprintf("-port=%d", port)
+	containerPorts := []corev1.ContainerPort{}
+	var remoteStatusPort int32
+	for _, port := range ports {
+		if port.Arg != "" {
+			arg, err := portArg(port)
+			if err != nil {
+				conditions.MarkFalse(gameServer, gamev1alpha1.TemplatesRenderedCondition, gamev1alpha1.InvalidTemplateReason, clusterv1.ConditionSeverityError,
+					"%s", err.Error())
+				return ctrl.Result{}, nil
+			}
 
-	netimguiPort := (port - r.GamePortMin) + r.NetImguiPortMin
-	netimguiPortArg := fmt.Sprintf("-NetImguiClientPort=%d", netimguiPort)
+			args = append(args, arg)
+		}
 
-	remoteStatusPort := (port - r.GamePortMin) + r.StatusPortMin
-	remoteStatusPortArg := fmt.Sprintf("-RemoteStatusPort=%d", remoteStatusPort)
+		if port.Name == StatusPortName {
+			remoteStatusPort = port.Port
+		}
 
-	args = append(args, portArg, netimguiPortArg, remoteStatusPortArg)
+		containerPorts = append(containerPorts, corev1.ContainerPort{
+			Name:          port.Name,
+			ContainerPort: port.Port,
+			Protocol:      port.Protocol,
+		})
+	}
 
 	storageKey := gameServer.Spec.DisplayName
 	if storageKey == "" {
@@ -270,6 +551,27 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 	// set up OTEL_RESOURCE_ATTRIBUTES env var
 	otelResourceAttributes := fmt.Sprintf("game_server_name=%s", gameServer.GetName())
 
+	env := []corev1.EnvVar{
+		{
+			Name:  "OTEL_RESOURCE_ATTRIBUTES",
+			Value: otelResourceAttributes,
+		},
+	}
+
+	for _, envVar := range gameServer.Spec.Env {
+		rendered, err := renderTemplate(envVar.Value, templateData)
+		if err != nil {
+			conditions.MarkFalse(gameServer, gamev1alpha1.TemplatesRenderedCondition, gamev1alpha1.InvalidTemplateReason, clusterv1.ConditionSeverityError,
+				"failed to expand env var %s: %s", envVar.Name, err.Error())
+			return ctrl.Result{}, nil
+		}
+
+		envVar.Value = rendered
+		env = append(env, envVar)
+	}
+
+	conditions.MarkTrue(gameServer, gamev1alpha1.TemplatesRenderedCondition)
+
 	// We need to create a Pod.
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -291,14 +593,26 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 		Spec: corev1.PodSpec{
 			Volumes: []corev1.Volume{
 				{
-					Name: "external-ip",
+					Name: "fellowship",
 					VolumeSource: corev1.VolumeSource{
-						DownwardAPI: &corev1.DownwardAPIVolumeSource{
-							Items: []corev1.DownwardAPIVolumeFile{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
 								{
-									Path: "external-ip",
-									FieldRef: &corev1.ObjectFieldSelector{
-										FieldPath: "metadata.annotations['believer.dev/external-ip']",
+									DownwardAPI: &corev1.DownwardAPIProjection{
+										Items: []corev1.DownwardAPIVolumeFile{
+											{
+												Path: "external-ip",
+												FieldRef: &corev1.ObjectFieldSelector{
+													FieldPath: "metadata.annotations['believer.dev/external-ip']",
+												},
+											},
+											{
+												Path: "draining",
+												FieldRef: &corev1.ObjectFieldSelector{
+													FieldPath: fmt.Sprintf("metadata.annotations['%s']", DrainingAnnotation),
+												},
+											},
+										},
 									},
 								},
 							},
@@ -311,35 +625,14 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 					Name:  "game-server",
 					Image: image,
 					Args:  args,
-					Env: []corev1.EnvVar{
-						{
-							Name:  "OTEL_RESOURCE_ATTRIBUTES",
-							Value: otelResourceAttributes,
-						},
-					},
+					Env:   env,
 					VolumeMounts: []corev1.VolumeMount{
 						{
-							Name:      "external-ip",
+							Name:      "fellowship",
 							MountPath: "/var/run/fellowship",
 						},
 					},
-					Ports: []corev1.ContainerPort{
-						{
-							Name:          "game",
-							ContainerPort: port,
-							Protocol:      corev1.ProtocolUDP,
-						},
-						{
-							Name:          "netimgui",
-							ContainerPort: netimguiPort,
-							Protocol:      corev1.ProtocolTCP,
-						},
-						{
-							Name:          "status",
-							ContainerPort: remoteStatusPort,
-							Protocol:      corev1.ProtocolTCP,
-						},
-					},
+					Ports: containerPorts,
 				},
 			},
 			HostNetwork: true,
@@ -357,6 +650,26 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 		},
 	}
 
+	// playtest servers get their group's allowlist next to the external IP. It's optional so the Pod
+	// still starts if the ConfigMap is missing, and the file shows up once it's created.
+	if gameServer.Spec.Playtest != nil {
+		projected := pod.Spec.Volumes[0].Projected
+		projected.Sources = append(projected.Sources, corev1.VolumeProjection{
+			ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: allowlistConfigMapName(gameServer),
+				},
+				Optional: pointer.Bool(true),
+				Items: []corev1.KeyToPath{
+					{
+						Key:  AllowlistFileName,
+						Path: AllowlistFileName,
+					},
+				},
+			},
+		})
+	}
+
 	if gameServer.Spec.IncludeReadinessProbe {
 		pod.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
@@ -413,6 +726,60 @@ func (r *GameServerReconciler) reconcilePod(ctx context.Context, gameServer *gam
 	return ctrl.Result{}, nil
 }
 
+// rescheduleBackoff returns how long to wait before recreating a Pod after the given number of
+// consecutive port conflicts
+func (r *GameServerReconciler) rescheduleBackoff(attempts int32) time.Duration {
+	return exponentialBackoff(r.RescheduleBackoff, r.MaxRescheduleBackoff, attempts)
+}
+
+// pendingTimeoutBackoff returns how long to wait before recreating a Pod after the given number of
+// consecutive pending timeouts
+func pendingTimeoutBackoff(attempts int32) time.Duration {
+	return exponentialBackoff(basePendingTimeoutBackoff, maxPendingTimeoutBackoff, attempts)
+}
+
+// exponentialBackoff doubles base for each attempt after the first, up to limit if it's set
+func exponentialBackoff(base, limit time.Duration, attempts int32) time.Duration {
+	backoff := base
+	// stop doubling well before a time.Duration could overflow
+	for i := int32(1); i < attempts && i < 32; i++ {
+		backoff *= 2
+		if limit > 0 && backoff >= limit {
+			return limit
+		}
+	}
+
+	return backoff
+}
+
+// imagePullFailure returns the waiting reason and message of the first container that failed to pull its image
+func imagePullFailure(pod *corev1.Pod) (string, string) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && imagePullFailureReasons[status.State.Waiting.Reason] {
+			return status.State.Waiting.Reason, status.State.Waiting.Message
+		}
+	}
+
+	return "", ""
+}
+
+// gameServerFailure returns the reason and message of the first GameServer condition that is failing with
+// a Warning or Error severity
+func gameServerFailure(gameServer *gamev1alpha1.GameServer) (string, string) {
+	for _, t := range []clusterv1.ConditionType{gamev1alpha1.TemplatesRenderedCondition, gamev1alpha1.PodScheduledCondition, gamev1alpha1.ImagePulledCondition} {
+		condition := conditions.Get(gameServer, t)
+		if condition == nil || condition.Status != corev1.ConditionFalse {
+			continue
+		}
+
+		if condition.Severity == clusterv1.ConditionSeverityWarning || condition.Severity == clusterv1.ConditionSeverityError {
+			return condition.Reason, condition.Message
+		}
+	}
+
+	return "", ""
+}
+
 func (r *GameServerReconciler) getExternalIPForNode(ctx context.Context, nodeName string) (string, error) {
 	node := &corev1.Node{}
 	if err := r.Client.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
@@ -433,6 +800,7 @@ func (r *GameServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&gamev1alpha1.GameServer{}).
 		Owns(&corev1.Pod{}).
+		Owns(&corev1.ConfigMap{}).
 		Complete(r)
 }
 
@@ -0,0 +1,455 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+var _ = Describe("GameServerController", func() {
+	Describe("Reschedule Backoff", func() {
+		var gsr *GameServerReconciler
+
+		BeforeEach(func() {
+			gsr = &GameServerReconciler{
+				RescheduleBackoff:    time.Second,
+				MaxRescheduleBackoff: time.Minute,
+			}
+		})
+
+		It("should double the delay on each attempt", func() {
+			Expect(gsr.rescheduleBackoff(1)).To(Equal(1 * time.Second))
+			Expect(gsr.rescheduleBackoff(2)).To(Equal(2 * time.Second))
+			Expect(gsr.rescheduleBackoff(4)).To(Equal(8 * time.Second))
+		})
+
+		It("should cap the delay", func() {
+			Expect(gsr.rescheduleBackoff(7)).To(Equal(time.Minute))
+			Expect(gsr.rescheduleBackoff(1000)).To(Equal(time.Minute))
+		})
+
+		Context("when there is no cap", func() {
+			BeforeEach(func() {
+				gsr.MaxRescheduleBackoff = 0
+			})
+
+			It("should keep doubling without overflowing", func() {
+				Expect(gsr.rescheduleBackoff(10)).To(Equal(512 * time.Second))
+				Expect(gsr.rescheduleBackoff(1000)).To(BeNumerically(">", 0))
+			})
+		})
+	})
+
+	Describe("Restarts", func() {
+		It("should give a server that ran out of reschedule attempts a fresh set", func() {
+			gsr := &GameServerReconciler{MaxRescheduleAttempts: 3}
+			now := metav1.Now()
+			gameServer := &gamev1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "restart",
+					Namespace:   "default",
+					Annotations: map[string]string{RestartAnnotation: "true"},
+				},
+				Status: gamev1alpha1.GameServerStatus{
+					RescheduleAttempts: 3,
+					LastPortConflict:   &now,
+				},
+			}
+			conditions.MarkFalse(gameServer, gamev1alpha1.PodScheduledCondition, gamev1alpha1.RescheduleAttemptsExceededReason, clusterv1.ConditionSeverityError, "")
+
+			result, err := gsr.restartPod(context.Background(), gameServer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(BeTrue())
+			Expect(gameServer.GetAnnotations()).NotTo(HaveKey(RestartAnnotation))
+			Expect(gameServer.Status.RescheduleAttempts).To(BeZero())
+			Expect(gameServer.Status.LastPortConflict).To(BeNil())
+			Expect(conditions.Get(gameServer, gamev1alpha1.PodScheduledCondition)).To(BeNil())
+		})
+	})
+
+	Describe("Pending Timeout Backoff", func() {
+		It("should grow with each timeout in a row", func() {
+			Expect(pendingTimeoutBackoff(1)).To(Equal(time.Minute))
+			Expect(pendingTimeoutBackoff(2)).To(Equal(2 * time.Minute))
+			Expect(pendingTimeoutBackoff(3)).To(Equal(4 * time.Minute))
+			Expect(pendingTimeoutBackoff(100)).To(Equal(maxPendingTimeoutBackoff))
+		})
+	})
+
+	Describe("Pending Pods", func() {
+		var gsr *GameServerReconciler
+		var gameServer *gamev1alpha1.GameServer
+		var pod *corev1.Pod
+
+		createPendingPod := func(status corev1.PodStatus) {
+			pod = &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:    "default",
+					GenerateName: "pending-gameserver-",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "gameserver", Image: "gameserver:1111aaaa"}},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), pod)).To(Succeed())
+
+			status.Phase = corev1.PodPending
+			pod.Status = status
+			Expect(k8sClient.Status().Update(context.Background(), pod)).To(Succeed())
+
+			gameServer.Status.PodRef = &corev1.LocalObjectReference{Name: pod.GetName()}
+		}
+
+		BeforeEach(func() {
+			gsr = &GameServerReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			gameServer = &gamev1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "pending-gameserver",
+				},
+			}
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(context.Background(), &corev1.Pod{}, client.InNamespace("default"))).To(Succeed())
+		})
+
+		It("should report image pull failures", func() {
+			createPendingPod(corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "gameserver",
+					Image: "gameserver:1111aaaa",
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "manifest unknown"},
+					},
+				}},
+			})
+
+			_, err := gsr.reconcilePod(context.Background(), gameServer)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(conditions.IsFalse(gameServer, gamev1alpha1.ImagePulledCondition)).To(BeTrue())
+			Expect(conditions.GetReason(gameServer, gamev1alpha1.ImagePulledCondition)).To(Equal("ErrImagePull"))
+			Expect(conditions.GetMessage(gameServer, gamev1alpha1.ImagePulledCondition)).To(Equal("manifest unknown"))
+		})
+
+		It("should report pods the scheduler can't place", func() {
+			createPendingPod(corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{
+					Type:    corev1.PodScheduled,
+					Status:  corev1.ConditionFalse,
+					Reason:  corev1.PodReasonUnschedulable,
+					Message: "0/3 nodes are available: 3 Insufficient cpu.",
+				}},
+			})
+
+			_, err := gsr.reconcilePod(context.Background(), gameServer)
+			Expect(err).ToNot(HaveOccurred())
+
+			// only port conflicts are retried by the operator, anything else is left to the scheduler
+			Expect(gameServer.Status.PodRef).ToNot(BeNil())
+			Expect(conditions.GetReason(gameServer, gamev1alpha1.PodScheduledCondition)).To(Equal(gamev1alpha1.PodUnschedulableReason))
+			Expect(conditions.GetMessage(gameServer, gamev1alpha1.PodScheduledCondition)).To(Equal("0/3 nodes are available: 3 Insufficient cpu."))
+		})
+
+		It("should recreate pods pending for too long after a growing backoff", func() {
+			gameServer.Spec.PendingTimeout = &metav1.Duration{Duration: time.Nanosecond}
+
+			createPendingPod(corev1.PodStatus{})
+			result, err := gsr.reconcilePod(context.Background(), gameServer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+			Expect(gameServer.Status.PodRef).To(BeNil())
+			Expect(gameServer.Status.PendingTimeouts).To(Equal(int32(1)))
+			Expect(conditions.GetReason(gameServer, gamev1alpha1.PodScheduledCondition)).To(Equal(gamev1alpha1.PendingTimeoutReason))
+
+			// no new Pod until the backoff is up
+			result, err = gsr.reconcilePod(context.Background(), gameServer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(gameServer.Status.PodRef).To(BeNil())
+
+			createPendingPod(corev1.PodStatus{})
+			result, err = gsr.reconcilePod(context.Background(), gameServer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(2 * time.Minute))
+			Expect(gameServer.Status.PendingTimeouts).To(Equal(int32(2)))
+		})
+	})
+
+	Describe("Port Allocation", func() {
+		var gsr *GameServerReconciler
+		var gameServer *gamev1alpha1.GameServer
+
+		BeforeEach(func() {
+			gsr = &GameServerReconciler{
+				GamePortMin:     7700,
+				GamePortMax:     7800,
+				NetImguiPortMin: 7800,
+				StatusPortMin:   9000,
+			}
+
+			gameServer = &gamev1alpha1.GameServer{
+				Spec: gamev1alpha1.GameServerSpec{
+					Ports: []gamev1alpha1.GameServerPort{
+						{
+							Name:     "voice",
+							Protocol: corev1.ProtocolUDP,
+							MinPort:  10000,
+							Policy:   gamev1alpha1.PortPolicyOffset,
+							Arg:      "-VoicePort=%d",
+						},
+						{
+							Name:    "game",
+							MinPort: 1,
+						},
+					},
+				},
+			}
+		})
+
+		It("should line up offset ports with the game port", func() {
+			ports := map[string]int32{}
+			for _, port := range gsr.allocatePorts(context.Background(), gameServer) {
+				ports[port.Name] = port.Port
+			}
+
+			Expect(ports).To(HaveLen(4))
+			Expect(ports[GamePortName]).To(BeNumerically(">=", 7700))
+			Expect(ports[GamePortName]).To(BeNumerically("<", 7800))
+
+			offset := ports[GamePortName] - 7700
+			Expect(ports[NetImguiPortName]).To(Equal(7800 + offset))
+			Expect(ports[StatusPortName]).To(Equal(9000 + offset))
+			Expect(ports["voice"]).To(Equal(10000 + offset))
+		})
+
+		It("should pass ports in their arg", func() {
+			arg, err := portArg(allocatedPort{GameServerPort: gamev1alpha1.GameServerPort{Name: "voice", Arg: "-VoicePort=%d"}, Port: 10042})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(arg).To(Equal("-VoicePort=10042"))
+
+			arg, err = portArg(allocatedPort{GameServerPort: gamev1alpha1.GameServerPort{Name: "voice", Arg: "-VoicePort=%d -Loss=5%%"}, Port: 10042})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(arg).To(Equal("-VoicePort=10042 -Loss=5%"))
+		})
+
+		It("should refuse args that aren't a single %d", func() {
+			for _, format := range []string{"-VoicePort", "-VoicePort=%s", "-VoicePort=%d -Other=%d", "-VoicePort=%5d"} {
+				_, err := portArg(allocatedPort{GameServerPort: gamev1alpha1.GameServerPort{Name: "voice", Arg: format}, Port: 10042})
+				Expect(err).To(HaveOccurred(), format)
+			}
+		})
+
+		It("should report invalid port args without creating a Pod", func() {
+			gameServer.Spec.Ports[0].Arg = "-VoicePort"
+
+			// the Pod would be created with the reconciler's client, which isn't set
+			result, err := gsr.reconcilePod(context.Background(), gameServer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(gameServer.Status.PodRef).To(BeNil())
+			Expect(conditions.GetReason(gameServer, gamev1alpha1.TemplatesRenderedCondition)).To(Equal(gamev1alpha1.InvalidTemplateReason))
+		})
+
+		It("should pick random ports within the range", func() {
+			port := gsr.pickPort(gamev1alpha1.GameServerPort{
+				MinPort: 20000,
+				MaxPort: 20010,
+				Policy:  gamev1alpha1.PortPolicyRandom,
+			}, 0)
+
+			Expect(port).To(BeNumerically(">=", 20000))
+			Expect(port).To(BeNumerically("<", 20010))
+		})
+	})
+
+	Describe("Templated Arguments", func() {
+		var data *gameServerTemplateData
+
+		BeforeEach(func() {
+			gameServer := &gamev1alpha1.GameServer{
+				Spec: gamev1alpha1.GameServerSpec{
+					Version: "linux-server-420e4db0",
+					Playtest: &gamev1alpha1.GameServerPlaytest{
+						Name:       "friday",
+						Group:      "Group 2",
+						GroupIndex: 2,
+						Users:      []string{"alice", "bob"},
+					},
+				},
+			}
+			gameServer.SetName("friday-group-2")
+
+			data = newGameServerTemplateData(gameServer, []allocatedPort{
+				{GameServerPort: gamev1alpha1.GameServerPort{Name: "voice"}, Port: 7912},
+			})
+		})
+
+		It("should leave plain arguments alone", func() {
+			Expect(renderTemplate("-log", data)).To(Equal("-log"))
+		})
+
+		It("should expand playtest and server context", func() {
+			Expect(renderTemplate("-SessionName={{ .Playtest }}-{{ .GroupIndex }}", data)).To(Equal("-SessionName=friday-2"))
+			Expect(renderTemplate("-Users={{ join .Users \",\" }}", data)).To(Equal("-Users=alice,bob"))
+			Expect(renderTemplate("-VoicePort={{ index .Ports \"voice\" }}", data)).To(Equal("-VoicePort=7912"))
+		})
+
+		It("should not offer the external IP, which isn't known when the container starts", func() {
+			_, err := renderTemplate("-ip={{ .ExternalIP }}", data)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should tell which templates depend on the group", func() {
+			Expect(referencesGroup("-log", "-SessionName={{ .Playtest }}")).To(BeFalse())
+			Expect(referencesGroup("-log", "-SessionName={{ .Playtest }}-{{ .GroupIndex }}")).To(BeTrue())
+			Expect(referencesGroup("-Users={{ join .Users \",\" }}")).To(BeTrue())
+			Expect(referencesGroup("-Group=.Group")).To(BeFalse())
+		})
+
+		It("should fail on unknown fields", func() {
+			_, err := renderTemplate("{{ .Nope }}", data)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should report invalid templates without creating a Pod", func() {
+			gsr := &GameServerReconciler{GamePortMin: 7700, GamePortMax: 7800}
+			gameServer := &gamev1alpha1.GameServer{
+				Spec: gamev1alpha1.GameServerSpec{
+					CmdArgs: []string{"-log", "-Session={{ .Nope }}"},
+				},
+			}
+
+			// the Pod would be created with the reconciler's client, which isn't set
+			result, err := gsr.reconcilePod(context.Background(), gameServer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(gameServer.Status.PodRef).To(BeNil())
+			Expect(conditions.GetReason(gameServer, gamev1alpha1.TemplatesRenderedCondition)).To(Equal(gamev1alpha1.InvalidTemplateReason))
+
+			reason, _ := gameServerFailure(gameServer)
+			Expect(reason).To(Equal(gamev1alpha1.InvalidTemplateReason))
+			Expect(isFatalServerReason(reason)).To(BeTrue())
+		})
+	})
+
+	Describe("Session Status", func() {
+		It("should record the player count reported by the server", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/status"))
+				_, _ = w.Write([]byte(`{"players": 3, "acceptingJoiners": false, "map": "/Game/Maps/Test"}`))
+			}))
+			defer server.Close()
+
+			host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+			Expect(err).ToNot(HaveOccurred())
+			statusPort, err := strconv.Atoi(port)
+			Expect(err).ToNot(HaveOccurred())
+
+			gameServer := &gamev1alpha1.GameServer{}
+			gameServer.Status.InternalIP = host
+			gameServer.Status.StatusPort = int32(statusPort)
+
+			r := &GameServerReconciler{HTTPClient: server.Client()}
+			r.updateSessionStatus(context.Background(), gameServer)
+
+			Expect(gameServer.Status.Players).To(Equal(pointer.Int32(3)))
+			Expect(gameServer.Status.AcceptingJoiners).To(Equal(pointer.Bool(false)))
+			Expect(gameServer.Status.LastSessionStatusTime).ToNot(BeNil())
+		})
+
+		It("should not poll again within the interval", func() {
+			polls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				polls++
+				_, _ = w.Write([]byte(`{"players": 3}`))
+			}))
+			defer server.Close()
+
+			host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+			Expect(err).ToNot(HaveOccurred())
+			statusPort, err := strconv.Atoi(port)
+			Expect(err).ToNot(HaveOccurred())
+
+			gameServer := &gamev1alpha1.GameServer{}
+			gameServer.Status.InternalIP = host
+			gameServer.Status.StatusPort = int32(statusPort)
+
+			r := &GameServerReconciler{HTTPClient: server.Client(), SessionStatusInterval: time.Minute}
+			Expect(r.pollSessionStatus(context.Background(), gameServer)).To(Equal(time.Minute))
+			Expect(polls).To(Equal(1))
+
+			// the status update wakes the controller straight back up
+			Expect(r.pollSessionStatus(context.Background(), gameServer)).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(polls).To(Equal(1))
+
+			gameServer.Status.LastSessionStatusPollTime = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+			r.pollSessionStatus(context.Background(), gameServer)
+			Expect(polls).To(Equal(2))
+		})
+
+		It("should not poll a server that doesn't answer again within the interval", func() {
+			polls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				polls++
+				<-req.Context().Done()
+			}))
+			defer server.Close()
+
+			host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+			Expect(err).ToNot(HaveOccurred())
+			statusPort, err := strconv.Atoi(port)
+			Expect(err).ToNot(HaveOccurred())
+
+			gameServer := &gamev1alpha1.GameServer{}
+			gameServer.Status.InternalIP = host
+			gameServer.Status.StatusPort = int32(statusPort)
+
+			httpClient := server.Client()
+			httpClient.Timeout = 50 * time.Millisecond
+
+			r := &GameServerReconciler{HTTPClient: httpClient, SessionStatusInterval: time.Minute}
+			r.pollSessionStatus(context.Background(), gameServer)
+			Expect(polls).To(Equal(1))
+			Expect(gameServer.Status.LastSessionStatusTime).To(BeNil())
+			Expect(gameServer.Status.LastSessionStatusPollTime).ToNot(BeNil())
+
+			Expect(r.pollSessionStatus(context.Background(), gameServer)).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(polls).To(Equal(1))
+		})
+
+		It("should keep the last known counts when the server doesn't answer", func() {
+			gameServer := &gamev1alpha1.GameServer{}
+			gameServer.Status.InternalIP = "127.0.0.1"
+			gameServer.Status.StatusPort = 1
+			gameServer.Status.Players = pointer.Int32(2)
+
+			r := &GameServerReconciler{}
+			r.updateSessionStatus(context.Background(), gameServer)
+
+			Expect(gameServer.Status.Players).To(Equal(pointer.Int32(2)))
+		})
+	})
+})
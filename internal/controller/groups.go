@@ -0,0 +1,456 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// maxGroups returns how many groups the playtest may expand to
+func maxGroups(playtest *gamev1alpha1.Playtest) int {
+	if playtest.Spec.MaxGroups < playtest.Spec.MinGroups {
+		return playtest.Spec.MinGroups
+	}
+
+	return playtest.Spec.MaxGroups
+}
+
+// openSlots returns how many more players fit in the playtest's groups
+func openSlots(playtest *gamev1alpha1.Playtest) int {
+	open := 0
+	for _, group := range playtest.Spec.Groups {
+		if n := playtest.Spec.PlayersPerGroup - len(group.Users); n > 0 {
+			open += n
+		}
+	}
+
+	return open
+}
+
+// wantedSlots returns how many open slots the playtest should keep: enough for everyone queued or
+// waitlisted, and at least the expansion policy's threshold
+func wantedSlots(playtest *gamev1alpha1.Playtest) int {
+	wanted := len(playtest.Spec.UsersToAutoAssign)
+	for _, party := range playtest.Spec.PartiesToAutoAssign {
+		wanted += len(party.Users)
+	}
+	for _, entry := range playtest.Status.Waitlist {
+		wanted += len(entry.Users)
+	}
+
+	if expansion := playtest.Spec.GroupExpansion; expansion != nil && expansion.MinOpenSlots > wanted {
+		wanted = expansion.MinOpenSlots
+	}
+
+	return wanted
+}
+
+// nextGroupName returns the lowest numbered default group name not already in use
+func nextGroupName(playtest *gamev1alpha1.Playtest) string {
+	used := map[string]bool{}
+	for _, group := range playtest.Spec.Groups {
+		used[group.Name] = true
+	}
+
+	for i := 1; ; i++ {
+		if name := fmt.Sprintf("Group %d", i); !used[name] {
+			return name
+		}
+	}
+}
+
+func addGroup(playtest *gamev1alpha1.Playtest) {
+	playtest.Spec.Groups = append(playtest.Spec.Groups, gamev1alpha1.PlaytestGroup{
+		Name:  nextGroupName(playtest),
+		Users: []string{},
+	})
+}
+
+// reconcileGroups keeps the playtest between MinGroups and MaxGroups, adding groups while it is short
+// on open slots and collapsing empty ones once it has started
+func (r *PlaytestReconciler) reconcileGroups(ctx context.Context, playtest *gamev1alpha1.Playtest) error {
+	log := log.FromContext(ctx)
+
+	for len(playtest.Spec.Groups) < playtest.Spec.MinGroups {
+		addGroup(playtest)
+	}
+
+	for len(playtest.Spec.Groups) > maxGroups(playtest) {
+		removeGroup(playtest, len(playtest.Spec.Groups)-1)
+	}
+
+	if playtest.Spec.PlayersPerGroup > 0 {
+		for len(playtest.Spec.Groups) < maxGroups(playtest) && openSlots(playtest) < wantedSlots(playtest) {
+			addGroup(playtest)
+
+			log.Info("expanding playtest", "group", playtest.Spec.Groups[len(playtest.Spec.Groups)-1].Name, "groups", len(playtest.Spec.Groups))
+		}
+
+		started := !time.Now().Before(playtest.Spec.StartTime.Time)
+		if expansion := playtest.Spec.GroupExpansion; expansion != nil && expansion.CollapseEmptyGroups && started {
+			for i := len(playtest.Spec.Groups) - 1; i >= 0 && len(playtest.Spec.Groups) > playtest.Spec.MinGroups; i-- {
+				if len(playtest.Spec.Groups[i].Users) > 0 {
+					continue
+				}
+
+				// don't collapse a group we'd need to add straight back
+				if openSlots(playtest)-playtest.Spec.PlayersPerGroup < wantedSlots(playtest) {
+					break
+				}
+
+				log.Info("collapsing empty group", "group", playtest.Spec.Groups[i].Name)
+
+				removeGroup(playtest, i)
+			}
+		}
+	}
+
+	pruneGroupStatuses(playtest)
+
+	return nil
+}
+
+// pruneGroupStatuses forgets about groups that no longer exist
+func pruneGroupStatuses(playtest *gamev1alpha1.Playtest) {
+	groupStatuses := []gamev1alpha1.PlaytestGroupStatus{}
+	for _, groupStatus := range playtest.Status.Groups {
+		if getGroup(playtest, groupStatus.Name) != nil {
+			groupStatuses = append(groupStatuses, groupStatus)
+		}
+	}
+	playtest.Status.Groups = groupStatuses
+}
+
+// removeGroup deletes the group at index i. Any users in it go back to the front of the auto-assign
+// queue. Its server is left for collectOrphanedServers to drain once the change is saved, and its status
+// for pruneGroupStatuses.
+func removeGroup(playtest *gamev1alpha1.Playtest, i int) {
+	group := playtest.Spec.Groups[i]
+
+	if len(group.Users) > 0 {
+		playtest.Spec.UsersToAutoAssign = append(append([]string{}, group.Users...), playtest.Spec.UsersToAutoAssign...)
+	}
+
+	playtest.Spec.Groups = append(playtest.Spec.Groups[:i], playtest.Spec.Groups[i+1:]...)
+}
+
+// invalidServerNameChars matches runs of characters that can't appear in a DNS-1123 label
+var invalidServerNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sanitizeServerName builds a valid DNS-1123 label for a group's GameServer. Names that are already
+// valid are used as is, otherwise the name is cleaned up, truncated and suffixed with a hash of key, so
+// groups that only differ in punctuation or past the length limit still get distinct names.
+func sanitizeServerName(playtestName, groupName, key string) string {
+	name := fmt.Sprintf("%s-%s", playtestName, strings.ReplaceAll(strings.ToLower(groupName), " ", "-"))
+	if key == groupName && len(validation.IsDNS1123Label(name)) == 0 {
+		return name
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	suffix := fmt.Sprintf("%08x", hash.Sum32())
+
+	name = invalidServerNameChars.ReplaceAllString(strings.ToLower(playtestName+"-"+groupName), "-")
+	if maxLength := validation.DNS1123LabelMaxLength - len(suffix) - 1; len(name) > maxLength {
+		name = name[:maxLength]
+	}
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return suffix
+	}
+
+	return name + "-" + suffix
+}
+
+// serverNameTaken returns true if a group other than groupName already uses the server name
+func serverNameTaken(playtest *gamev1alpha1.Playtest, groupName, name string) bool {
+	for _, groupStatus := range playtest.Status.Groups {
+		if groupStatus.Name == groupName {
+			continue
+		}
+
+		if groupStatus.ServerName == name || (groupStatus.ServerRef != nil && groupStatus.ServerRef.Name == name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// groupServerName returns the name of the GameServer created for the group. Once a name is recorded in
+// the group's status it's used from then on.
+func groupServerName(playtest *gamev1alpha1.Playtest, group gamev1alpha1.PlaytestGroup) string {
+	if groupStatus := getGroupStatus(playtest, group.Name); groupStatus != nil {
+		if groupStatus.ServerName != "" {
+			return groupStatus.ServerName
+		}
+
+		// servers created before names were recorded
+		if groupStatus.ServerRef != nil {
+			return groupStatus.ServerRef.Name
+		}
+	}
+
+	name := sanitizeServerName(playtest.GetName(), group.Name, group.Name)
+	for i := 1; serverNameTaken(playtest, group.Name, name); i++ {
+		name = sanitizeServerName(playtest.GetName(), group.Name, fmt.Sprintf("%s/%d", group.Name, i))
+	}
+
+	return name
+}
+
+// userParties maps each user auto-assigned as part of a party to the party's name
+func userParties(playtest *gamev1alpha1.Playtest) map[string]string {
+	parties := map[string]string{}
+	for _, assignment := range playtest.Status.Assignments {
+		if assignment.Party != "" {
+			parties[assignment.User] = assignment.Party
+		}
+	}
+
+	return parties
+}
+
+// rebalanceUnit is a set of users in a group that a rebalance moves together
+type rebalanceUnit struct {
+	party string
+	users []string
+}
+
+// rebalanceUnits splits a group's users into each party in the group and every other user on their own
+func rebalanceUnits(group gamev1alpha1.PlaytestGroup, parties map[string]string) []rebalanceUnit {
+	units := []rebalanceUnit{}
+	partyUnits := map[string]int{}
+	for _, user := range group.Users {
+		party, ok := parties[user]
+		if !ok {
+			units = append(units, rebalanceUnit{users: []string{user}})
+			continue
+		}
+
+		i, ok := partyUnits[party]
+		if !ok {
+			i = len(units)
+			partyUnits[party] = i
+			units = append(units, rebalanceUnit{party: party})
+		}
+		units[i].users = append(units[i].users, user)
+	}
+
+	return units
+}
+
+// pickRebalanceUnit returns the unit of from that best halves the difference in size between from and
+// to, or nil if moving any of them wouldn't bring the groups closer or doesn't fit in to
+func pickRebalanceUnit(playtest *gamev1alpha1.Playtest, units []rebalanceUnit, from, to gamev1alpha1.PlaytestGroup) *rebalanceUnit {
+	diff := len(from.Users) - len(to.Users)
+
+	var best *rebalanceUnit
+	for i := range units {
+		size := len(units[i].users)
+		if size >= diff {
+			continue
+		}
+
+		if playtest.Spec.PlayersPerGroup > 0 && len(to.Users)+size > playtest.Spec.PlayersPerGroup {
+			continue
+		}
+
+		if best == nil || abs(2*size-diff) < abs(2*len(best.users)-diff) {
+			best = &units[i]
+		}
+	}
+
+	return best
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+// rebalanceGroups evens out the playtest's group sizes by moving users from the fullest groups to the
+// emptiest, keeping parties together. It returns where each moved user ended up.
+func rebalanceGroups(playtest *gamev1alpha1.Playtest) []gamev1alpha1.PlaytestGroupMove {
+	parties := userParties(playtest)
+	groups := playtest.Spec.Groups
+
+	moves := []gamev1alpha1.PlaytestGroupMove{}
+	recordMove := func(user, party, from, to string) {
+		for i := range moves {
+			if moves[i].User != user {
+				continue
+			}
+
+			// a user moved twice only moved from where they started
+			if moves[i].From == to {
+				moves = append(moves[:i], moves[i+1:]...)
+			} else {
+				moves[i].To = to
+			}
+
+			return
+		}
+
+		moves = append(moves, gamev1alpha1.PlaytestGroupMove{User: user, Party: party, From: from, To: to})
+	}
+
+	// every move makes the groups strictly more even, so this always ends
+	for {
+		order := make([]int, len(groups))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return len(groups[order[i]].Users) > len(groups[order[j]].Users)
+		})
+
+		moved := false
+		for _, from := range order {
+			for j := len(order) - 1; j >= 0 && !moved; j-- {
+				to := order[j]
+				if len(groups[from].Users)-len(groups[to].Users) <= 1 {
+					break
+				}
+
+				unit := pickRebalanceUnit(playtest, rebalanceUnits(groups[from], parties), groups[from], groups[to])
+				if unit == nil {
+					continue
+				}
+
+				moving := map[string]bool{}
+				for _, user := range unit.users {
+					moving[user] = true
+				}
+
+				remaining := []string{}
+				for _, user := range groups[from].Users {
+					if !moving[user] {
+						remaining = append(remaining, user)
+					}
+				}
+				groups[from].Users = remaining
+				groups[to].Users = append(groups[to].Users, unit.users...)
+
+				for _, user := range unit.users {
+					recordMove(user, unit.party, groups[from].Name, groups[to].Name)
+				}
+
+				moved = true
+			}
+
+			if moved {
+				break
+			}
+		}
+
+		if !moved {
+			return moves
+		}
+	}
+}
+
+// ConfirmServerChangeAnnotation confirms a version or map change for playtests with the Immediate server
+// change policy. It is removed once the change is applied.
+const ConfirmServerChangeAnnotation = "believer.dev/confirm-server-change"
+
+// serverChangeState returns what a version or map change to the group's running server is waiting on,
+// or an empty state if the server may be replaced now
+func serverChangeState(playtest *gamev1alpha1.Playtest, group gamev1alpha1.PlaytestGroup, now time.Time) gamev1alpha1.ServerChangeState {
+	if now.Before(playtest.Spec.StartTime.Time) {
+		return ""
+	}
+
+	switch playtest.Spec.ServerChangePolicy {
+	case gamev1alpha1.ServerChangePolicyBlock:
+		return gamev1alpha1.ServerChangeBlocked
+	case gamev1alpha1.ServerChangePolicyImmediate:
+		if _, ok := playtest.GetAnnotations()[ConfirmServerChangeAnnotation]; !ok {
+			return gamev1alpha1.ServerChangeWaitingForConfirmation
+		}
+	default:
+		// the server knows who's actually connected, so trust it over who's assigned when it says
+		if groupStatus := getGroupStatus(playtest, group.Name); groupStatus != nil && groupStatus.Players != nil {
+			if *groupStatus.Players > 0 {
+				return gamev1alpha1.ServerChangeWaitingForEmpty
+			}
+		} else if len(group.Users) > 0 {
+			return gamev1alpha1.ServerChangeWaitingForEmpty
+		}
+	}
+
+	return ""
+}
+
+// groupServerSettings are the settings a group's server is created with
+type groupServerSettings struct {
+	// Commit is the version as given, before it's expanded to an image tag
+	Commit                string
+	Version               string
+	Map                   string
+	CmdArgs               []string
+	IncludeReadinessProbe bool
+}
+
+// resolveGroupServer returns the group's server settings, falling back to the playtest's for any the
+// group doesn't override
+func resolveGroupServer(playtest *gamev1alpha1.Playtest, group gamev1alpha1.PlaytestGroup) groupServerSettings {
+	settings := groupServerSettings{
+		Commit:                playtest.Spec.Version,
+		Map:                   playtest.Spec.Map,
+		CmdArgs:               playtest.Spec.GameServerCmdArgs,
+		IncludeReadinessProbe: pointer.BoolDeref(playtest.Spec.IncludeReadinessProbe, false),
+	}
+
+	if group.Version != "" {
+		settings.Commit = group.Version
+	}
+
+	if group.Map != "" {
+		settings.Map = group.Map
+	}
+
+	if group.GameServerCmdArgs != nil {
+		settings.CmdArgs = group.GameServerCmdArgs
+	}
+
+	if group.IncludeReadinessProbe != nil {
+		settings.IncludeReadinessProbe = *group.IncludeReadinessProbe
+	}
+
+	settings.Version = settings.Commit
+	if len(settings.Commit) == 8 {
+		settings.Version = fmt.Sprintf("linux-server-%s", settings.Commit)
+	}
+
+	return settings
+}
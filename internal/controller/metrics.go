@@ -0,0 +1,60 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// portConflictsTotal counts every Pod recreated because no node had its ports free. A steadily
+	// climbing rate is the signal to widen the port range.
+	portConflictsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "f11r_gameserver_port_conflicts_total",
+			Help: "Number of GameServer Pods recreated due to a host port conflict",
+		},
+		[]string{"namespace"},
+	)
+
+	// rescheduleAttempts tracks the current consecutive reschedule attempts of each GameServer
+	rescheduleAttempts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "f11r_gameserver_reschedule_attempts",
+			Help: "Consecutive port conflict reschedule attempts for a GameServer",
+		},
+		[]string{"namespace", "gameserver"},
+	)
+
+	// rescheduleExhaustedTotal counts GameServers marked unschedulable after running out of attempts
+	rescheduleExhaustedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "f11r_gameserver_reschedule_exhausted_total",
+			Help: "Number of GameServers that gave up rescheduling after too many port conflicts",
+		},
+		[]string{"namespace"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		portConflictsTotal,
+		rescheduleAttempts,
+		rescheduleExhaustedTotal,
+	)
+}
@@ -18,21 +18,23 @@ package controller
 
 import (
 	"context"
-	"errors"
-	"fmt"
-	"math/rand"
-	"strings"
+	"net"
+	"strconv"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
 )
@@ -41,12 +43,33 @@ import (
 type PlaytestReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// DefaultRetention is how long ended playtests are kept before being archived and deleted,
+	// unless they set their own retention
+	DefaultRetention time.Duration
+
+	// Assigners are additional group assignment strategies playtests may use, keyed by name
+	Assigners map[gamev1alpha1.AssignmentStrategy]GroupAssigner
+
+	// DefaultProvisioningLeadTime is how long before the start time group servers are created,
+	// unless the playtest sets its own lead time
+	DefaultProvisioningLeadTime time.Duration
+	// ProvisioningBatchSize is how many group servers are created at a time. Zero creates them all at once.
+	ProvisioningBatchSize int
+	// ProvisioningBatchInterval is the delay between batches of group servers
+	ProvisioningBatchInterval time.Duration
+
+	// Recorder records Events on playtests, such as group server failovers
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=game.believer.dev,resources=playtests,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=game.believer.dev,resources=playtests/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=game.believer.dev,resources=playtests/finalizers,verbs=update
 //+kubebuilder:rbac:groups=game.believer.dev,resources=gameservers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=game.believer.dev,resources=playtesttemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -65,11 +88,57 @@ func (r *PlaytestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Fill in settings from the playtest's template. These are never written back to the spec.
+	template, templateErr := r.resolvePlaytestTemplate(ctx, playtest)
+	if templateErr != nil && !apierrors.IsNotFound(templateErr) {
+		return ctrl.Result{}, templateErr
+	}
+
+	// Without its template, a playtest that hasn't started yet waits for it rather than running with
+	// settings that are missing. Creating the template triggers another reconcile.
+	if templateErr != nil && template == nil && time.Now().Before(playtest.Spec.StartTime.Time) {
+		log.Info("waiting for playtest template", "template", playtest.Spec.Template)
+
+		patchHelper, err := patch.NewHelper(playtest, r.Client)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		markTemplateResolved(playtest, template, templateErr)
+		setPlaytestPhase(playtest, r.provisioningLeadTime(playtest))
+
+		if err := patchHelper.Patch(ctx, playtest); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: r.untilNextTransition(playtest, time.Now())}, nil
+	}
+
+	// Settle group membership first, as its own patch, so it can't overwrite concurrent edits to the groups
+	membership, err := r.reconcileMembership(ctx, playtest)
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			log.Info("playtest changed during group assignment, retrying")
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	// the patched object doesn't carry the template's settings
+	if template != nil {
+		applyPlaytestTemplate(playtest, &template.Spec)
+	}
+
 	patchHelper, err := patch.NewHelper(playtest, r.Client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	playtest.Status = membership.Status
+	playtest.Status.Template = template
+	markTemplateResolved(playtest, template, templateErr)
+
 	// No matter what happens during reconciliation, we want to try to patch the object at the end and catch updates
 	defer func() {
 		if err := patchHelper.Patch(ctx, playtest); err != nil {
@@ -77,18 +146,80 @@ func (r *PlaytestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 	}()
 
-	return r.reconcilePlaytest(ctx, playtest)
+	result, err := r.reconcilePlaytest(ctx, playtest)
+	if err != nil || (result.Requeue && result.RequeueAfter == 0) {
+		return result, err
+	}
+
+	// nothing in the cluster changes when the playtest is due to start or end, so come back for it
+	result.RequeueAfter = soonest(result.RequeueAfter, r.untilNextTransition(playtest, time.Now()))
+
+	return result, nil
+}
+
+// reconcileMembership carries out any action annotations, then sizes the playtest's groups and
+// auto-assigns everyone waiting, in a single pass.
+// Spec changes are patched with optimistic locking, and playtest is updated to the patched object.
+// The returned copy carries the status changes, which are left to the caller to patch.
+func (r *PlaytestReconciler) reconcileMembership(ctx context.Context, playtest *gamev1alpha1.Playtest) (*gamev1alpha1.Playtest, error) {
+	membership := playtest.DeepCopy()
+
+	// action annotations are saved along with the membership changes they cause
+	results, pending := r.runActions(ctx, membership)
+
+	// groups aren't managed once the playtest is over, if it doesn't want servers, or while it's suspended
+	// or they're locked
+	if !membership.Spec.DisableGameServers && !membership.Spec.Suspend && !membership.Spec.LockMembership && time.Now().Before(playtestEndTime(membership)) {
+		if err := r.reconcileGroups(ctx, membership); err != nil {
+			return nil, err
+		}
+
+		r.assignQueued(ctx, membership)
+	}
+
+	if equality.Semantic.DeepEqual(playtest.Spec, membership.Spec) && equality.Semantic.DeepEqual(playtest.Annotations, membership.Annotations) {
+		return membership, nil
+	}
+
+	// the status goes first, since users taken off the queue are only kept track of there once they're
+	// waitlisted or assigned. If the spec patch then fails, they're still queued and picked up again.
+	// Both are locked, so nothing can change in between.
+	base := playtest.DeepCopy()
+	if !equality.Semantic.DeepEqual(playtest.Status, membership.Status) {
+		patched := membership.DeepCopy()
+		patched.Status.Waitlist = pendingWaitlist(playtest.Status.Waitlist, membership.Status.Waitlist)
+		if err := r.Client.Status().Patch(ctx, patched, client.MergeFromWithOptions(playtest, client.MergeFromWithOptimisticLock{})); err != nil {
+			return nil, err
+		}
+
+		base.ResourceVersion = patched.ResourceVersion
+	}
+
+	patched := membership.DeepCopy()
+	if err := r.Client.Patch(ctx, patched, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+		return nil, err
+	}
+
+	*playtest = *patched
+
+	// the annotations are gone for good now, so the rest can't be carried out twice
+	results = append(results, r.runPendingActions(ctx, membership, pending)...)
+	r.recordActionEvents(playtest, results)
+
+	return membership, nil
 }
 
 func (r *PlaytestReconciler) reconcilePlaytest(ctx context.Context, playtest *gamev1alpha1.Playtest) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
-	// If playtest is prunable and is more than 24 hours old, delete it
-	if _, ok := playtest.Annotations["believer.dev/do-not-prune"]; !ok {
-		now := time.Now()
-		createdOn := playtest.Spec.StartTime.Time
+	// If playtest is prunable and has been over for longer than its retention, archive and delete it
+	if prunable(playtest) {
+		if !time.Now().Before(playtestEndTime(playtest).Add(r.retention(playtest))) {
+			if err := archivePlaytest(ctx, r.Client, playtest); err != nil {
+				log.Error(err, "failed to archive old playtest")
+				return ctrl.Result{}, err
+			}
 
-		if now.Sub(createdOn) > 24*time.Hour {
 			if err := r.Delete(ctx, playtest); err != nil {
 				log.Error(err, "failed to delete old playtest")
 				return ctrl.Result{}, err
@@ -97,100 +228,161 @@ func (r *PlaytestReconciler) reconcilePlaytest(ctx context.Context, playtest *ga
 		}
 	}
 
-    // If we don't want servers, group management below
-    if playtest.Spec.DisableGameServers {
-        return ctrl.Result{}, nil
-    }
+	// Whatever happens below, keep the phase and conditions current
+	defer setPlaytestPhase(playtest, r.provisioningLeadTime(playtest))
 
-	// First, set up default groups
-	if len(playtest.Spec.Groups) == 0 {
-		for i := 1; i <= playtest.Spec.MinGroups; i++ {
-			playtest.Spec.Groups = append(playtest.Spec.Groups, gamev1alpha1.PlaytestGroup{
-				Name:  fmt.Sprintf("Group %d", i),
-				Users: []string{},
-			})
-		}
-	} else if len(playtest.Spec.Groups) < playtest.Spec.MinGroups {
-		for i := len(playtest.Spec.Groups); i < playtest.Spec.MinGroups; i++ {
-			log.Info("adding group", "group", fmt.Sprintf("Group %d", i))
+	// Once the playtest is over, tear down its servers but keep the Playtest around for reporting
+	if !time.Now().Before(playtestEndTime(playtest)) {
+		recordGroupOutcomes(playtest)
 
-			playtest.Spec.Groups = append(playtest.Spec.Groups, gamev1alpha1.PlaytestGroup{
-				Name:  fmt.Sprintf("Group %d", i+1),
-				Users: []string{},
-			})
-		}
-	} else if len(playtest.Spec.Groups) > playtest.Spec.MinGroups {
-		playtest.Spec.Groups = playtest.Spec.Groups[:playtest.Spec.MinGroups]
+		return r.teardownGroupServers(ctx, playtest)
 	}
 
-	// Then, auto assign any users, requeue each time for sanity
-	if playtest.Spec.UsersToAutoAssign != nil && len(playtest.Spec.UsersToAutoAssign) > 0 {
-		user := playtest.Spec.UsersToAutoAssign[0]
+	// If we don't want servers, group management below
+	if playtest.Spec.DisableGameServers {
+		return ctrl.Result{}, nil
+	}
 
-		openGroups := []int{}
-		for i := 0; i < len(playtest.Spec.Groups); i++ {
-			group := &playtest.Spec.Groups[i]
-			if len(group.Users) < playtest.Spec.PlayersPerGroup {
-				openGroups = append(openGroups, i)
-			}
+	// While suspended, keep the servers we have, or drain them if asked to, but don't create any
+	if playtest.Spec.Suspend {
+		if playtest.Spec.DrainOnSuspend {
+			return r.teardownGroupServers(ctx, playtest)
 		}
 
-		// Shuffle for random assignment
-		if len(openGroups) == 0 {
-			log.Error(errors.New("no open groups"), "no open groups")
+		return ctrl.Result{}, r.observeGroupServers(ctx, playtest)
+	}
 
-			return ctrl.Result{}, nil
+	// Create a gameserver for each group, if it doesn't exist, a batch at a time
+	batch := r.newProvisioningBatch(playtest)
+	shouldRequeue := false
+	for i, group := range playtest.Spec.Groups {
+		requeue, err := r.reconcileGroupServer(ctx, playtest, group, i+1, batch)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		shouldRequeue = shouldRequeue || requeue
+	}
+
+	// a confirmation only covers the change it was given for
+	if _, ok := playtest.GetAnnotations()[ConfirmServerChangeAnnotation]; ok && !time.Now().Before(playtest.Spec.StartTime.Time) {
+		delete(playtest.Annotations, ConfirmServerChangeAnnotation)
+	}
+
+	updateLateJoiners(playtest)
+
+	// Keep spares warm alongside the group servers
+	if time.Now().UTC().Add(r.provisioningLeadTime(playtest)).After(playtest.Spec.StartTime.Time) {
+		if err := r.reconcileSpares(ctx, playtest, playtest.Spec.Spares, batch); err != nil {
+			return ctrl.Result{}, err
 		}
+	}
 
-		r := rand.New(rand.NewSource((time.Now().UnixNano())))
-		groupIndex := r.Intn(len(openGroups))
+	if batch.created > 0 {
+		playtest.Status.LastProvisioningBatchTime = &metav1.Time{Time: time.Now()}
+	}
 
-		group := &playtest.Spec.Groups[openGroups[groupIndex]]
-		group.Users = append(group.Users, user)
+	// Then, clean up servers left behind by renamed or removed groups
+	drainWait, err := r.collectOrphanedServers(ctx, playtest)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-		playtest.Spec.UsersToAutoAssign = playtest.Spec.UsersToAutoAssign[1:]
+	if batch.deferred {
+		log.Info("waiting to provision next batch of gameservers", "after", batch.wait)
 
-		return ctrl.Result{Requeue: true}, nil
+		return ctrl.Result{Requeue: true, RequeueAfter: soonest(drainWait, batch.wait)}, nil
 	}
 
-	// Create a gameserver for each group, if it doesn't exist
-	shouldRequeue := false
+	return ctrl.Result{Requeue: shouldRequeue, RequeueAfter: drainWait}, nil
+}
+
+// collectOrphanedServers drains and deletes the playtest's GameServers that no longer belong to one of
+// its groups. It returns how long until the next of them is due to be deleted.
+func (r *PlaytestReconciler) collectOrphanedServers(ctx context.Context, playtest *gamev1alpha1.Playtest) (time.Duration, error) {
+	log := log.FromContext(ctx)
+
+	pruneGroupStatuses(playtest)
+
+	// a group's server may not be in its status yet if the last status update was lost
+	mapped := map[string]bool{}
 	for _, group := range playtest.Spec.Groups {
-		var err error
+		mapped[groupServerName(playtest, group)] = true
+	}
+	for _, groupStatus := range playtest.Status.Groups {
+		if groupStatus.ServerRef != nil {
+			mapped[groupStatus.ServerRef.Name] = true
+		}
+	}
+
+	gameServers := &gamev1alpha1.GameServerList{}
+	if err := r.List(ctx, gameServers, client.InNamespace(playtest.GetNamespace()), client.MatchingLabels{
+		PlaytestLabel: playtest.GetName(),
+	}); err != nil {
+		return 0, err
+	}
 
-		shouldRequeue, err = r.reconcileGroupServer(ctx, playtest, group)
+	requeueAfter := time.Duration(0)
+	for i := range gameServers.Items {
+		gameServer := &gameServers.Items[i]
+
+		// the same goes for a spare claimed by one of the groups
+		if gameServer.Spec.Playtest != nil && gameServer.Spec.Playtest.Group != "" && getGroup(playtest, gameServer.Spec.Playtest.Group) != nil {
+			continue
+		}
+
+		if mapped[gameServer.GetName()] || gameServer.GetLabels()[SpareLabel] != "" || !metav1.IsControlledBy(gameServer, playtest) || !gameServer.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		log.Info("draining orphaned gameserver", "gameserver", gameServer.GetName())
+
+		remaining, err := r.drainGameServer(ctx, playtest.GetNamespace(), gameServer.GetName(), teardownGracePeriod(playtest))
 		if err != nil {
-			return ctrl.Result{}, err
+			return 0, err
 		}
-	}
 
-	if len(playtest.Status.Groups) > playtest.Spec.MinGroups {
-		playtest.Status.Groups = playtest.Status.Groups[:playtest.Spec.MinGroups]
+		if remaining > 0 && (requeueAfter == 0 || remaining < requeueAfter) {
+			requeueAfter = remaining
+		}
 	}
 
-	return ctrl.Result{Requeue: shouldRequeue}, nil
+	return requeueAfter, nil
 }
 
-func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest *gamev1alpha1.Playtest, group gamev1alpha1.PlaytestGroup) (bool, error) {
+func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest *gamev1alpha1.Playtest, group gamev1alpha1.PlaytestGroup, groupIndex int, batch *provisioningBatch) (bool, error) {
 	log := log.FromContext(ctx)
 
 	// Find the group in the status
 	groupStatus := getGroupStatus(playtest, group.Name)
 	if groupStatus == nil {
-		groupStatus = &gamev1alpha1.PlaytestGroupStatus{
+		playtest.Status.Groups = append(playtest.Status.Groups, gamev1alpha1.PlaytestGroupStatus{
 			Name: group.Name,
-		}
-		playtest.Status.Groups = append(playtest.Status.Groups, *groupStatus)
+		})
+		groupStatus = &playtest.Status.Groups[len(playtest.Status.Groups)-1]
+	}
+
+	if groupStatus.ServerName == "" {
+		groupStatus.ServerName = groupServerName(playtest, group)
 	}
 
 	groupStatus.Users = group.Users
 
-	playtestServerVersion := playtest.Spec.Version
-	if len(playtest.Spec.Version) == 8 {
-		playtestServerVersion = fmt.Sprintf("linux-server-%s", playtest.Spec.Version)
+	// a playtest extended after it ended has no outcome yet
+	groupStatus.Outcome = nil
+
+	settings := resolveGroupServer(playtest, group)
+
+	groupServerPlaytest := &gamev1alpha1.GameServerPlaytest{
+		Name:       playtest.GetName(),
+		Group:      group.Name,
+		GroupIndex: groupIndex,
+		Users:      group.Users,
 	}
 
 	if groupStatus.ServerRef != nil {
+		gone := false
+
 		gameServer := &gamev1alpha1.GameServer{}
 		if err := r.Client.Get(ctx, client.ObjectKey{
 			Name:      groupStatus.ServerRef.Name,
@@ -199,14 +391,26 @@ func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest
 			if !apierrors.IsNotFound(err) {
 				return false, err
 			} else {
-				groupStatus.ServerRef = nil
+				gone = true
 			}
 		}
 
-		groupStatus.Ready = gameServer.Status.Ready
+		observeGroupServer(groupStatus, gameServer)
+
+		if gone || groupServerFailed(gameServer) {
+			swapped, err := r.failoverGroupServer(ctx, playtest, group, groupStatus, settings, groupServerPlaytest)
+			if err != nil {
+				return false, err
+			}
+
+			// without a spare, a server that's gone is recreated below
+			if !swapped && gone {
+				groupStatus.ServerRef = nil
+			}
+		}
 	}
 
-	if time.Now().UTC().Add(10 * time.Minute).After(playtest.Spec.StartTime.Time) {
+	if time.Now().UTC().Add(r.provisioningLeadTime(playtest)).After(playtest.Spec.StartTime.Time) {
 		if groupStatus.ServerRef != nil {
 			gameServer := &gamev1alpha1.GameServer{}
 			if err := r.Client.Get(ctx, client.ObjectKey{
@@ -219,8 +423,9 @@ func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest
 					groupStatus.ServerRef = nil
 				}
 			} else {
-				if gameServer.Spec.Version != playtestServerVersion || gameServer.Spec.Map != playtest.Spec.Map {
-					log.Info("deleting gameserver for group", "group", group.Name)
+				// a server drained while the playtest was suspended or over is replaced now it's back
+				if _, ok := gameServer.GetAnnotations()[DrainingAnnotation]; ok {
+					log.Info("replacing drained gameserver for group", "group", group.Name)
 
 					if err := r.Client.Delete(ctx, gameServer); err != nil {
 						return false, err
@@ -230,6 +435,38 @@ func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest
 
 					return true, nil
 				}
+
+				groupStatus.Version = gameServer.Spec.Version
+				groupStatus.Map = gameServer.Spec.Map
+				groupStatus.ServerChange = ""
+
+				if gameServer.Spec.Version != settings.Version || gameServer.Spec.Map != settings.Map {
+					groupStatus.ServerChange = serverChangeState(playtest, group, time.Now())
+					if groupStatus.ServerChange == "" {
+						log.Info("deleting gameserver for group", "group", group.Name)
+
+						if err := r.Client.Delete(ctx, gameServer); err != nil {
+							return false, err
+						}
+
+						groupStatus.ServerRef = nil
+						groupStatus.Version = ""
+						groupStatus.Map = ""
+
+						return true, nil
+					}
+				}
+
+				// keep the server's group membership current so its allowlist follows group changes
+				if !equality.Semantic.DeepEqual(gameServer.Spec.Playtest, groupServerPlaytest) {
+					log.Info("updating gameserver group membership", "group", group.Name)
+
+					before := gameServer.DeepCopy()
+					gameServer.Spec.Playtest = groupServerPlaytest
+					if err := r.Client.Patch(ctx, gameServer, client.MergeFrom(before)); err != nil {
+						return false, err
+					}
+				}
 			}
 
 			if groupStatus.Ready {
@@ -239,16 +476,19 @@ func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest
 			return true, nil
 		}
 
+		if !batch.take() {
+			return false, nil
+		}
+
 		log.Info("creating gameserver for group", "group", group.Name)
 
-		formattedGroupName := strings.ReplaceAll(strings.ToLower(group.Name), " ", "-")
 		gameServer := &gamev1alpha1.GameServer{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      fmt.Sprintf("%s-%s", playtest.GetName(), formattedGroupName),
+				Name:      groupStatus.ServerName,
 				Namespace: playtest.GetNamespace(),
 				Labels: map[string]string{
-					"believer.dev/playtest": playtest.GetName(),
-					"believer.dev/commit":   playtest.Spec.Version,
+					PlaytestLabel:         playtest.GetName(),
+					"believer.dev/commit": settings.Commit,
 				},
 				OwnerReferences: []metav1.OwnerReference{
 					{
@@ -261,10 +501,14 @@ func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest
 				},
 			},
 			Spec: gamev1alpha1.GameServerSpec{
-				Version:               playtestServerVersion,
-				Map:                   playtest.Spec.Map,
-				IncludeReadinessProbe: playtest.Spec.IncludeReadinessProbe,
-				CmdArgs:               playtest.Spec.GameServerCmdArgs,
+				Version:               settings.Version,
+				Map:                   settings.Map,
+				IncludeReadinessProbe: settings.IncludeReadinessProbe,
+				CmdArgs:               settings.CmdArgs,
+				Env:                   playtest.Spec.GameServerEnv,
+				Ports:                 playtest.Spec.GameServerPorts,
+				PendingTimeout:        playtest.Spec.PendingTimeout,
+				Playtest:              groupServerPlaytest,
 			},
 		}
 
@@ -306,6 +550,264 @@ func (r *PlaytestReconciler) reconcileGroupServer(ctx context.Context, playtest
 	return false, nil
 }
 
+// observeGroupServer reports the state of the group's server in the group's status
+func observeGroupServer(groupStatus *gamev1alpha1.PlaytestGroupStatus, gameServer *gamev1alpha1.GameServer) {
+	groupStatus.Ready = gameServer.Status.Ready
+	groupStatus.Reason, groupStatus.Message = gameServerFailure(gameServer)
+	groupStatus.Players = gameServer.Status.Players
+	groupStatus.AcceptingJoiners = gameServer.Status.AcceptingJoiners
+
+	groupStatus.Address = ""
+	if gameServer.Status.IP != "" && gameServer.Status.Port != 0 {
+		groupStatus.Address = net.JoinHostPort(gameServer.Status.IP, strconv.Itoa(int(gameServer.Status.Port)))
+	}
+}
+
+// observeGroupServers refreshes the status of the groups' existing servers without changing them
+func (r *PlaytestReconciler) observeGroupServers(ctx context.Context, playtest *gamev1alpha1.Playtest) error {
+	for i := range playtest.Status.Groups {
+		groupStatus := &playtest.Status.Groups[i]
+		if groupStatus.ServerRef == nil {
+			continue
+		}
+
+		gameServer := &gamev1alpha1.GameServer{}
+		if err := r.Client.Get(ctx, client.ObjectKey{
+			Name:      groupStatus.ServerRef.Name,
+			Namespace: playtest.GetNamespace(),
+		}, gameServer); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			groupStatus.ServerRef = nil
+		}
+
+		observeGroupServer(groupStatus, gameServer)
+	}
+
+	return nil
+}
+
+// failoverGroupServer swaps the group's dead server for a spare, returning false if there is none
+func (r *PlaytestReconciler) failoverGroupServer(ctx context.Context, playtest *gamev1alpha1.Playtest, group gamev1alpha1.PlaytestGroup, groupStatus *gamev1alpha1.PlaytestGroupStatus, settings groupServerSettings, groupServerPlaytest *gamev1alpha1.GameServerPlaytest) (bool, error) {
+	log := log.FromContext(ctx)
+
+	failedName := groupStatus.ServerRef.Name
+
+	// an earlier failover may have claimed a spare without getting to record it
+	spare, err := r.claimedServer(ctx, playtest, group.Name, failedName)
+	if err != nil {
+		return false, err
+	}
+
+	if spare == nil {
+		spare, err = r.claimSpare(ctx, playtest, settings, groupServerPlaytest)
+		if err != nil || spare == nil {
+			return false, err
+		}
+	}
+
+	log.Info("replacing failed gameserver with spare", "group", group.Name, "gameserver", failedName, "spare", spare.GetName())
+
+	failed := &gamev1alpha1.GameServer{}
+	failed.SetName(failedName)
+	failed.SetNamespace(playtest.GetNamespace())
+	if err := r.Client.Delete(ctx, failed); client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+
+	groupStatus.ServerRef = &corev1.LocalObjectReference{
+		Name: spare.GetName(),
+	}
+	observeGroupServer(groupStatus, spare)
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(playtest, corev1.EventTypeWarning, GroupServerFailoverReason,
+			"Replaced failed server %s for group %s with spare %s", failedName, group.Name, spare.GetName())
+	}
+
+	return true, nil
+}
+
+// retention returns how long the playtest is kept after it ends
+func (r *PlaytestReconciler) retention(playtest *gamev1alpha1.Playtest) time.Duration {
+	if playtest.Spec.Retention != nil {
+		return playtest.Spec.Retention.Duration
+	}
+
+	return r.DefaultRetention
+}
+
+// DoNotPruneAnnotation keeps an ended Playtest from ever being archived and deleted
+const DoNotPruneAnnotation = "believer.dev/do-not-prune"
+
+// prunable returns true if the playtest is deleted once its retention is up. Playtests created by a
+// PlaytestSchedule are left to the schedule's history limit instead.
+func prunable(playtest *gamev1alpha1.Playtest) bool {
+	_, scheduled := playtest.Labels[PlaytestScheduleLabel]
+
+	return !keptWhenEnded(playtest) && !scheduled
+}
+
+// keptWhenEnded returns true if the playtest is kept after it ends, by retention and a schedule's history
+// limit alike. Suspended playtests are kept until they're resumed, and annotated ones for good.
+func keptWhenEnded(playtest *gamev1alpha1.Playtest) bool {
+	_, ok := playtest.Annotations[DoNotPruneAnnotation]
+
+	return ok || playtest.Spec.Suspend
+}
+
+// untilNextTransition returns how long until the playtest's servers are due to be created, it starts,
+// ends or is due to be pruned, whichever is next. It returns zero if none of them are still to come.
+func (r *PlaytestReconciler) untilNextTransition(playtest *gamev1alpha1.Playtest, now time.Time) time.Duration {
+	end := playtestEndTime(playtest)
+	transitions := []time.Time{
+		playtest.Spec.StartTime.Add(-r.provisioningLeadTime(playtest)),
+		playtest.Spec.StartTime.Time,
+		end,
+	}
+
+	if prunable(playtest) {
+		transitions = append(transitions, end.Add(r.retention(playtest)))
+	}
+
+	next := time.Duration(0)
+	for _, transition := range transitions {
+		next = soonest(next, transition.Sub(now))
+	}
+
+	return next
+}
+
+// soonest returns the smaller of two delays, ignoring any that aren't positive
+func soonest(a, b time.Duration) time.Duration {
+	switch {
+	case b <= 0:
+		if a < 0 {
+			return 0
+		}
+		return a
+	case a <= 0 || b < a:
+		return b
+	default:
+		return a
+	}
+}
+
+// recordGroupOutcomes keeps the state of each group's server as the playtest ends, before teardown
+// clears it
+func recordGroupOutcomes(playtest *gamev1alpha1.Playtest) {
+	for i := range playtest.Status.Groups {
+		groupStatus := &playtest.Status.Groups[i]
+		if groupStatus.Outcome != nil {
+			continue
+		}
+
+		groupStatus.Outcome = &gamev1alpha1.PlaytestGroupOutcome{
+			Ready:   groupStatus.Ready,
+			Reason:  groupStatus.Reason,
+			Message: groupStatus.Message,
+		}
+		if groupStatus.ServerRef != nil {
+			groupStatus.Outcome.Server = groupStatus.ServerRef.Name
+		}
+	}
+}
+
+// teardownGracePeriod returns how long the playtest's servers are left draining before they're deleted
+func teardownGracePeriod(playtest *gamev1alpha1.Playtest) time.Duration {
+	if playtest.Spec.TeardownGracePeriod != nil {
+		return playtest.Spec.TeardownGracePeriod.Duration
+	}
+
+	return 0
+}
+
+// teardownGroupServers drains every group server and deletes it once the teardown grace period is up
+func (r *PlaytestReconciler) teardownGroupServers(ctx context.Context, playtest *gamev1alpha1.Playtest) (ctrl.Result, error) {
+	grace := teardownGracePeriod(playtest)
+
+	// spares have no players to drain
+	if err := r.reconcileSpares(ctx, playtest, 0, nil); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	requeueAfter := time.Duration(0)
+	for i := range playtest.Status.Groups {
+		groupStatus := &playtest.Status.Groups[i]
+		if groupStatus.ServerRef == nil {
+			continue
+		}
+
+		remaining, err := r.drainGameServer(ctx, playtest.GetNamespace(), groupStatus.ServerRef.Name, grace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if remaining > 0 {
+			if requeueAfter == 0 || remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			continue
+		}
+
+		groupStatus.ServerRef = nil
+		groupStatus.Ready = false
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// drainGameServer marks a GameServer as draining and deletes it once the grace period has passed since.
+// It returns how much of the grace period is left.
+func (r *PlaytestReconciler) drainGameServer(ctx context.Context, namespace, name string, grace time.Duration) (time.Duration, error) {
+	log := log.FromContext(ctx)
+
+	gameServer := &gamev1alpha1.GameServer{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, gameServer); err != nil {
+		return 0, client.IgnoreNotFound(err)
+	}
+
+	drainingSince, err := time.Parse(time.RFC3339, gameServer.GetAnnotations()[DrainingAnnotation])
+	if err != nil {
+		log.Info("draining gameserver", "gameserver", name, "grace", grace)
+
+		drainingSince = time.Now()
+
+		before := gameServer.DeepCopy()
+		if gameServer.Annotations == nil {
+			gameServer.Annotations = map[string]string{}
+		}
+		gameServer.Annotations[DrainingAnnotation] = drainingSince.UTC().Format(time.RFC3339)
+		if err := r.Client.Patch(ctx, gameServer, client.MergeFrom(before)); err != nil {
+			return 0, client.IgnoreNotFound(err)
+		}
+	}
+
+	if remaining := time.Until(drainingSince.Add(grace)); remaining > 0 {
+		return remaining, nil
+	}
+
+	log.Info("deleting drained gameserver", "gameserver", name)
+	if err := r.Client.Delete(ctx, gameServer); err != nil {
+		return 0, client.IgnoreNotFound(err)
+	}
+
+	return 0, nil
+}
+
+func getGroup(playtest *gamev1alpha1.Playtest, groupName string) *gamev1alpha1.PlaytestGroup {
+	for i := 0; i < len(playtest.Spec.Groups); i++ {
+		group := &playtest.Spec.Groups[i]
+		if group.Name == groupName {
+			return group
+		}
+	}
+
+	return nil
+}
+
 func getGroupStatus(playtest *gamev1alpha1.Playtest, groupName string) *gamev1alpha1.PlaytestGroupStatus {
 	for i := 0; i < len(playtest.Status.Groups); i++ {
 		group := &playtest.Status.Groups[i]
@@ -322,5 +824,9 @@ func (r *PlaytestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&gamev1alpha1.Playtest{}).
 		Owns(&gamev1alpha1.GameServer{}).
+		Watches(
+			&source.Kind{Type: &gamev1alpha1.PlaytestTemplate{}},
+			handler.EnqueueRequestsFromMapFunc(r.playtestsForTemplate),
+		).
 		Complete(r)
 }
@@ -2,14 +2,25 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
+	"time"
 
 	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
@@ -116,4 +127,1891 @@ var _ = Describe("PlaytestController", func() {
 			})
 		})
 	})
+
+	Describe("Assignment Strategies", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					PlayersPerGroup: 3,
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1", Users: []string{"alice", "bob"}},
+						{Name: "Group 2", Users: []string{"carol"}},
+						{Name: "Group 3", Users: []string{"dave"}},
+					},
+				},
+			}
+		})
+
+		It("should fill the emptiest group first", func() {
+			Expect(LeastFilledAssigner{}.Assign(playtest, []string{"erin"}, []int{0, 1, 2})).To(Equal(1))
+		})
+
+		It("should go round robin from the last assignment", func() {
+			recordAssignment(playtest, "dave", "", "Group 2", gamev1alpha1.AssignmentStrategyRoundRobin, time.Now())
+			Expect(RoundRobinAssigner{}.Assign(playtest, []string{"erin"}, []int{0, 1, 2})).To(Equal(2))
+
+			recordAssignment(playtest, "erin", "", "Group 3", gamev1alpha1.AssignmentStrategyRoundRobin, time.Now())
+			Expect(RoundRobinAssigner{}.Assign(playtest, []string{"frank"}, []int{0, 1, 2})).To(Equal(0))
+		})
+
+		It("should assign deterministically when seeded", func() {
+			playtest.Spec.AssignmentSeed = 42
+			first := SeededAssigner{}.Assign(playtest, []string{"erin"}, []int{0, 1, 2})
+			Expect(SeededAssigner{}.Assign(playtest, []string{"erin"}, []int{0, 1, 2})).To(Equal(first))
+		})
+
+		It("should keep only the latest assignment of each user", func() {
+			recordAssignment(playtest, "erin", "", "Group 2", gamev1alpha1.AssignmentStrategyRandom, time.Now())
+			recordAssignment(playtest, "erin", "", "Group 3", gamev1alpha1.AssignmentStrategyRandom, time.Now())
+
+			Expect(playtest.Status.Assignments).To(HaveLen(1))
+			Expect(playtest.Status.Assignments[0].Group).To(Equal("Group 3"))
+		})
+
+		It("should keep parties together", func() {
+			Expect(assignUsers(playtest, "squad", []string{"erin", "frank"}, gamev1alpha1.AssignmentStrategyLeastFilled, LeastFilledAssigner{})).To(Succeed())
+
+			Expect(playtest.Spec.Groups[1].Users).To(Equal([]string{"carol", "erin", "frank"}))
+			Expect(playtest.Status.Assignments).To(HaveLen(2))
+			Expect(playtest.Status.Assignments[0].Party).To(Equal("squad"))
+		})
+
+		It("should add a group for a party that doesn't fit", func() {
+			playtest.Spec.MaxGroups = 4
+			Expect(assignUsers(playtest, "squad", []string{"erin", "frank", "grace"}, gamev1alpha1.AssignmentStrategyRandom, RandomAssigner{})).To(Succeed())
+
+			Expect(playtest.Spec.Groups).To(HaveLen(4))
+			Expect(playtest.Spec.Groups[3].Users).To(Equal([]string{"erin", "frank", "grace"}))
+		})
+
+		It("should split a party only as a last resort", func() {
+			Expect(assignUsers(playtest, "squad", []string{"erin", "frank", "grace"}, gamev1alpha1.AssignmentStrategyRandom, RandomAssigner{})).To(Succeed())
+
+			Expect(playtest.Spec.Groups[1].Users).To(Equal([]string{"carol", "erin", "frank"}))
+			Expect(playtest.Spec.Groups[2].Users).To(Equal([]string{"dave", "grace"}))
+		})
+
+		It("should refuse a party bigger than the open slots", func() {
+			err := assignUsers(playtest, "squad", []string{"erin", "frank", "grace", "heidi", "ivan", "judy"}, gamev1alpha1.AssignmentStrategyRandom, RandomAssigner{})
+			Expect(err).To(HaveOccurred())
+			Expect(playtest.Spec.Groups[1].Users).To(Equal([]string{"carol"}))
+		})
+
+		It("should assign the whole queue in one pass", func() {
+			playtest.Spec.AssignmentStrategy = gamev1alpha1.AssignmentStrategyLeastFilled
+			playtest.Spec.PartiesToAutoAssign = []gamev1alpha1.PlaytestParty{{Name: "squad", Users: []string{"erin", "frank"}}}
+			playtest.Spec.UsersToAutoAssign = []string{"grace", "heidi", "ivan", "judy"}
+			(&PlaytestReconciler{}).assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Spec.PartiesToAutoAssign).To(BeEmpty())
+			Expect(playtest.Spec.UsersToAutoAssign).To(BeEmpty())
+			Expect(playtest.Spec.Groups[0].Users).To(Equal([]string{"alice", "bob", "heidi"}))
+			Expect(playtest.Spec.Groups[1].Users).To(Equal([]string{"carol", "erin", "frank"}))
+			Expect(playtest.Spec.Groups[2].Users).To(Equal([]string{"dave", "grace", "ivan"}))
+			Expect(playtest.Status.Waitlist).To(HaveLen(1))
+			Expect(playtest.Status.Waitlist[0].Users).To(Equal([]string{"judy"}))
+		})
+
+		It("should place waitlisted users first once there's room", func() {
+			addToWaitlist(playtest, "", []string{"erin"}, "no open groups", time.Now())
+			playtest.Spec.UsersToAutoAssign = []string{"frank", "grace", "heidi", "ivan", "judy"}
+			(&PlaytestReconciler{}).assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Status.Assignments[0].User).To(Equal("erin"))
+			Expect(playtest.Status.Waitlist).To(HaveLen(1))
+			Expect(playtest.Status.Waitlist[0].Users).To(Equal([]string{"judy"}))
+		})
+
+		It("should not assign users who are already in a group", func() {
+			playtest.Spec.AssignmentStrategy = gamev1alpha1.AssignmentStrategyLeastFilled
+			playtest.Spec.UsersToAutoAssign = []string{"alice", "erin"}
+			(&PlaytestReconciler{}).assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Spec.Groups[0].Users).To(Equal([]string{"alice", "bob"}))
+			Expect(playtest.Status.Assignments).To(HaveLen(1))
+			Expect(playtest.Status.Assignments[0].User).To(Equal("erin"))
+			Expect(playtest.Spec.Groups[1].Users).To(Equal([]string{"carol", "erin"}))
+		})
+
+		It("should not waitlist users twice", func() {
+			playtest.Spec.Groups[1].Users = []string{"carol", "erin", "frank"}
+			playtest.Spec.Groups[2].Users = []string{"dave", "grace", "heidi"}
+			playtest.Spec.Groups[0].Users = []string{"alice", "bob", "ivan"}
+			addToWaitlist(playtest, "", []string{"judy"}, "no open groups", time.Now())
+			playtest.Spec.UsersToAutoAssign = []string{"judy"}
+			(&PlaytestReconciler{}).assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Spec.UsersToAutoAssign).To(BeEmpty())
+			Expect(playtest.Status.Waitlist).To(HaveLen(1))
+			Expect(playtest.Status.Waitlist[0].Users).To(Equal([]string{"judy"}))
+		})
+
+		It("should drop waitlisted users who are already in a group", func() {
+			addToWaitlist(playtest, "squad", []string{"alice", "erin"}, "no open groups", time.Now())
+			(&PlaytestReconciler{}).assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Status.Waitlist).To(BeEmpty())
+			Expect(playtest.Status.Assignments).To(HaveLen(1))
+			Expect(playtest.Status.Assignments[0].User).To(Equal("erin"))
+		})
+
+		It("should keep users leaving the waitlist on it until their group is saved", func() {
+			before := []gamev1alpha1.PlaytestWaitlistEntry{
+				{Users: []string{"erin"}},
+				{Users: []string{"judy"}, Reason: "no open groups"},
+			}
+			after := []gamev1alpha1.PlaytestWaitlistEntry{
+				{Users: []string{"judy"}, Reason: "still no open groups"},
+				{Users: []string{"frank"}},
+			}
+
+			Expect(pendingWaitlist(before, after)).To(Equal([]gamev1alpha1.PlaytestWaitlistEntry{
+				{Users: []string{"judy"}, Reason: "still no open groups"},
+				{Users: []string{"frank"}},
+				{Users: []string{"erin"}},
+			}))
+		})
+
+		It("should prefer strategies registered on the reconciler", func() {
+			ptr := &PlaytestReconciler{
+				Assigners: map[gamev1alpha1.AssignmentStrategy]GroupAssigner{
+					"Custom": LeastFilledAssigner{},
+				},
+			}
+
+			playtest.Spec.AssignmentStrategy = "Custom"
+			_, assigner, ok := ptr.groupAssigner(playtest)
+			Expect(ok).To(BeTrue())
+			Expect(assigner).To(Equal(LeastFilledAssigner{}))
+
+			playtest.Spec.AssignmentStrategy = "Nope"
+			_, _, ok = ptr.groupAssigner(playtest)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Group Expansion", func() {
+		var ptr *PlaytestReconciler
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			ptr = &PlaytestReconciler{}
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					MinGroups:       2,
+					MaxGroups:       4,
+					PlayersPerGroup: 2,
+					StartTime:       metav1.NewTime(time.Now().Add(time.Hour)),
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1", Users: []string{"alice", "bob"}},
+						{Name: "Group 2", Users: []string{"carol", "dave"}},
+					},
+				},
+			}
+		})
+
+		It("should add groups for users waiting to be assigned", func() {
+			playtest.Spec.UsersToAutoAssign = []string{"erin", "frank", "grace"}
+			Expect(ptr.reconcileGroups(context.Background(), playtest)).To(Succeed())
+
+			Expect(playtest.Spec.Groups).To(HaveLen(4))
+			Expect(playtest.Spec.Groups[3].Name).To(Equal("Group 4"))
+		})
+
+		It("should add groups for waitlisted users", func() {
+			addToWaitlist(playtest, "squad", []string{"erin", "frank"}, "no open groups", time.Now())
+			Expect(ptr.reconcileGroups(context.Background(), playtest)).To(Succeed())
+
+			Expect(playtest.Spec.Groups).To(HaveLen(3))
+		})
+
+		It("should keep the open slot threshold without going over MaxGroups", func() {
+			playtest.Spec.GroupExpansion = &gamev1alpha1.PlaytestGroupExpansion{MinOpenSlots: 10}
+			Expect(ptr.reconcileGroups(context.Background(), playtest)).To(Succeed())
+
+			Expect(playtest.Spec.Groups).To(HaveLen(4))
+		})
+
+		It("should collapse empty extra groups after start", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-time.Minute))
+			playtest.Spec.GroupExpansion = &gamev1alpha1.PlaytestGroupExpansion{CollapseEmptyGroups: true}
+			playtest.Spec.Groups = append(playtest.Spec.Groups,
+				gamev1alpha1.PlaytestGroup{Name: "Group 3"},
+				gamev1alpha1.PlaytestGroup{Name: "Group 4", Users: []string{"erin"}},
+			)
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{{Name: "Group 3"}}
+			Expect(ptr.reconcileGroups(context.Background(), playtest)).To(Succeed())
+
+			Expect(playtest.Spec.Groups).To(HaveLen(3))
+			Expect(playtest.Spec.Groups[2].Name).To(Equal("Group 4"))
+			Expect(playtest.Status.Groups).To(BeEmpty())
+		})
+
+		It("should leave the servers of removed groups to be drained", func() {
+			playtest.Spec.MaxGroups = 1
+			playtest.Spec.MinGroups = 1
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 2", ServerRef: &corev1.LocalObjectReference{Name: "group-2"}},
+			}
+
+			// the reconciler has no client, so deleting the server would panic
+			Expect(ptr.reconcileGroups(context.Background(), playtest)).To(Succeed())
+
+			Expect(playtest.Spec.Groups).To(HaveLen(1))
+			Expect(playtest.Spec.UsersToAutoAssign).To(Equal([]string{"carol", "dave"}))
+			Expect(playtest.Status.Groups).To(BeEmpty())
+		})
+	})
+
+	Describe("Playtest Templates", func() {
+		var playtest *gamev1alpha1.Playtest
+		var template *gamev1alpha1.PlaytestTemplateSpec
+
+		BeforeEach(func() {
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					Template:        "weekly",
+					PlayersPerGroup: 6,
+				},
+			}
+
+			template = &gamev1alpha1.PlaytestTemplateSpec{
+				Map:                   "/Game/Levels/MyMap",
+				MinGroups:             4,
+				PlayersPerGroup:       4,
+				GameServerCmdArgs:     []string{"-log"},
+				IncludeReadinessProbe: true,
+			}
+		})
+
+		It("should fill in unset settings", func() {
+			applyPlaytestTemplate(playtest, template)
+
+			Expect(playtest.Spec.Map).To(Equal("/Game/Levels/MyMap"))
+			Expect(playtest.Spec.MinGroups).To(Equal(4))
+			Expect(playtest.Spec.GameServerCmdArgs).To(Equal([]string{"-log"}))
+			Expect(playtest.Spec.IncludeReadinessProbe).To(Equal(pointer.Bool(true)))
+		})
+
+		It("should let the playtest override the template", func() {
+			playtest.Spec.GameServerCmdArgs = []string{}
+			playtest.Spec.IncludeReadinessProbe = pointer.Bool(false)
+			applyPlaytestTemplate(playtest, template)
+
+			Expect(playtest.Spec.PlayersPerGroup).To(Equal(6))
+			Expect(playtest.Spec.GameServerCmdArgs).To(Equal([]string{}))
+			Expect(playtest.Spec.IncludeReadinessProbe).To(Equal(pointer.Bool(false)))
+		})
+
+		It("should use the recorded configuration once started", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-time.Minute))
+			playtest.Status.Template = &gamev1alpha1.PlaytestTemplateStatus{
+				Name: "weekly",
+				Spec: gamev1alpha1.PlaytestTemplateSpec{Map: "/Game/Levels/OldMap", MinGroups: 2},
+			}
+
+			// the template itself isn't looked up, so no client is needed
+			resolved, err := (&PlaytestReconciler{}).resolvePlaytestTemplate(context.Background(), playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved.Spec.Map).To(Equal("/Game/Levels/OldMap"))
+			Expect(playtest.Spec.Map).To(Equal("/Game/Levels/OldMap"))
+			Expect(playtest.Spec.MinGroups).To(Equal(2))
+		})
+
+		It("should report a missing template", func() {
+			notFound := apierrors.NewNotFound(gamev1alpha1.GroupVersion.WithResource("playtesttemplates").GroupResource(), "weekly")
+
+			markTemplateResolved(playtest, nil, nil)
+			Expect(conditions.IsTrue(playtest, gamev1alpha1.TemplateResolvedCondition)).To(BeTrue())
+
+			markTemplateResolved(playtest, &gamev1alpha1.PlaytestTemplateStatus{Name: "weekly", Generation: 3}, notFound)
+			Expect(conditions.GetReason(playtest, gamev1alpha1.TemplateResolvedCondition)).To(Equal(gamev1alpha1.TemplateNotFoundReason))
+			Expect(conditions.GetSeverity(playtest, gamev1alpha1.TemplateResolvedCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityWarning)))
+			Expect(conditions.GetMessage(playtest, gamev1alpha1.TemplateResolvedCondition)).To(ContainSubstring("generation 3"))
+
+			markTemplateResolved(playtest, nil, notFound)
+			Expect(conditions.GetSeverity(playtest, gamev1alpha1.TemplateResolvedCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityError)))
+
+			playtest.Spec.Template = ""
+			markTemplateResolved(playtest, nil, nil)
+			Expect(conditions.Has(playtest, gamev1alpha1.TemplateResolvedCondition)).To(BeFalse())
+		})
+	})
+
+	Describe("Missing Templates", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		reconcile := func() *gamev1alpha1.Playtest {
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(playtest)})
+			Expect(err).ToNot(HaveOccurred())
+
+			fetched := &gamev1alpha1.Playtest{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), fetched)).To(Succeed())
+
+			return fetched
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			r = &PlaytestReconciler{
+				Client:           k8sClient,
+				Scheme:           scheme.Scheme,
+				DefaultRetention: 24 * time.Hour,
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "templated-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Template:          "missing",
+					StartTime:         metav1.NewTime(time.Now().Add(time.Hour)),
+					MinGroups:         1,
+					Groups:            []gamev1alpha1.PlaytestGroup{{Name: "Group 1"}},
+					UsersToAutoAssign: []string{"alice"},
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			status := playtest.Status
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+
+			if status.Template != nil {
+				playtest.Status = status
+				Expect(k8sClient.Status().Update(ctx, playtest)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, playtest))).To(Succeed())
+		})
+
+		It("should wait for the template before the start", func() {
+			fetched := reconcile()
+
+			Expect(conditions.GetReason(fetched, gamev1alpha1.TemplateResolvedCondition)).To(Equal(gamev1alpha1.TemplateNotFoundReason))
+			Expect(fetched.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseScheduled))
+			Expect(fetched.Spec.UsersToAutoAssign).To(Equal([]string{"alice"}))
+		})
+
+		Context("when the template was resolved before", func() {
+			BeforeEach(func() {
+				playtest.Status.Template = &gamev1alpha1.PlaytestTemplateStatus{
+					Name:       "missing",
+					Generation: 2,
+					Spec:       gamev1alpha1.PlaytestTemplateSpec{PlayersPerGroup: 4},
+				}
+			})
+
+			It("should keep going with the settings it resolved", func() {
+				fetched := reconcile()
+
+				Expect(conditions.GetSeverity(fetched, gamev1alpha1.TemplateResolvedCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityWarning)))
+				Expect(fetched.Status.Template).ToNot(BeNil())
+				Expect(fetched.Spec.UsersToAutoAssign).To(BeEmpty())
+				Expect(fetched.Spec.Groups[0].Users).To(Equal([]string{"alice"}))
+				Expect(fetched.Spec.PlayersPerGroup).To(BeZero())
+			})
+		})
+
+		Context("when the playtest has ended", func() {
+			BeforeEach(func() {
+				playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-4 * time.Hour))
+				playtest.Spec.Duration = &metav1.Duration{Duration: time.Hour}
+			})
+
+			It("should still end", func() {
+				fetched := reconcile()
+
+				Expect(conditions.GetSeverity(fetched, gamev1alpha1.TemplateResolvedCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityError)))
+				Expect(fetched.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseEnded))
+			})
+		})
+	})
+
+	Describe("Group Overrides", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:               "420e4db0",
+					Map:                   "/Game/Levels/MyMap",
+					GameServerCmdArgs:     []string{"-log"},
+					IncludeReadinessProbe: pointer.Bool(true),
+				},
+			}
+		})
+
+		It("should fall back to the playtest's settings", func() {
+			settings := resolveGroupServer(playtest, gamev1alpha1.PlaytestGroup{Name: "Group 1"})
+
+			Expect(settings.Commit).To(Equal("420e4db0"))
+			Expect(settings.Version).To(Equal("linux-server-420e4db0"))
+			Expect(settings.Map).To(Equal("/Game/Levels/MyMap"))
+			Expect(settings.CmdArgs).To(Equal([]string{"-log"}))
+			Expect(settings.IncludeReadinessProbe).To(BeTrue())
+		})
+
+		It("should use the group's overrides", func() {
+			settings := resolveGroupServer(playtest, gamev1alpha1.PlaytestGroup{
+				Name:                  "Group 2",
+				Version:               "9f1c2b3a",
+				Map:                   "/Game/Levels/Control",
+				GameServerCmdArgs:     []string{},
+				IncludeReadinessProbe: pointer.Bool(false),
+			})
+
+			Expect(settings.Version).To(Equal("linux-server-9f1c2b3a"))
+			Expect(settings.Map).To(Equal("/Game/Levels/Control"))
+			Expect(settings.CmdArgs).To(BeEmpty())
+			Expect(settings.IncludeReadinessProbe).To(BeFalse())
+		})
+	})
+
+	Describe("Stored Overrides", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "override-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:           "420e4db0",
+					StartTime:         metav1.NewTime(time.Now().Add(time.Hour)),
+					MinGroups:         2,
+					PlayersPerGroup:   4,
+					GameServerCmdArgs: []string{},
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1"},
+						{Name: "Group 2", GameServerCmdArgs: []string{}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, playtest)).To(Succeed())
+		})
+
+		It("should keep empty args through the API server", func() {
+			stored := &gamev1alpha1.Playtest{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), stored)).To(Succeed())
+
+			Expect(stored.Spec.GameServerCmdArgs).To(Equal([]string{}))
+			Expect(stored.Spec.Groups[0].GameServerCmdArgs).To(BeNil())
+			Expect(stored.Spec.Groups[1].GameServerCmdArgs).To(Equal([]string{}))
+
+			// an empty list still overrides the template's args
+			applyPlaytestTemplate(stored, &gamev1alpha1.PlaytestTemplateSpec{GameServerCmdArgs: []string{"-log"}})
+			Expect(stored.Spec.GameServerCmdArgs).To(Equal([]string{}))
+		})
+
+		It("should keep an empty group override after the playtest's args change", func() {
+			stored := &gamev1alpha1.Playtest{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), stored)).To(Succeed())
+
+			before := stored.DeepCopy()
+			stored.Spec.GameServerCmdArgs = []string{"-log"}
+			Expect(k8sClient.Patch(ctx, stored, client.MergeFrom(before))).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), stored)).To(Succeed())
+			Expect(resolveGroupServer(stored, stored.Spec.Groups[0]).CmdArgs).To(Equal([]string{"-log"}))
+			Expect(resolveGroupServer(stored, stored.Spec.Groups[1]).CmdArgs).To(BeEmpty())
+		})
+	})
+
+	Describe("Provisioning Batches", func() {
+		var ptr *PlaytestReconciler
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			ptr = &PlaytestReconciler{
+				ProvisioningBatchSize:     2,
+				ProvisioningBatchInterval: 30 * time.Second,
+			}
+			playtest = &gamev1alpha1.Playtest{}
+		})
+
+		It("should stop creating servers once the batch is used up", func() {
+			batch := ptr.newProvisioningBatch(playtest)
+			Expect(batch.take()).To(BeTrue())
+			Expect(batch.take()).To(BeTrue())
+			Expect(batch.take()).To(BeFalse())
+			Expect(batch.created).To(Equal(2))
+			Expect(batch.deferred).To(BeTrue())
+		})
+
+		It("should wait out the interval after the last batch", func() {
+			playtest.Status.LastProvisioningBatchTime = &metav1.Time{Time: time.Now().Add(-10 * time.Second)}
+
+			batch := ptr.newProvisioningBatch(playtest)
+			Expect(batch.take()).To(BeFalse())
+			Expect(batch.wait).To(BeNumerically("<=", 20*time.Second))
+		})
+
+		It("should prefer the playtest's lead time", func() {
+			Expect(ptr.provisioningLeadTime(playtest)).To(Equal(defaultProvisioningLeadTime))
+
+			playtest.Spec.ProvisioningLeadTime = &metav1.Duration{Duration: 30 * time.Minute}
+			Expect(ptr.provisioningLeadTime(playtest)).To(Equal(30 * time.Minute))
+		})
+	})
+
+	Describe("Playtest Archive", func() {
+		It("should record groups and outcomes", func() {
+			playtest := &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:   "linux-server-420e4db0",
+					StartTime: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+					Duration:  &metav1.Duration{Duration: time.Hour},
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1", Users: []string{"alice"}},
+						{Name: "Group 2", Users: []string{"bob"}},
+					},
+				},
+				Status: gamev1alpha1.PlaytestStatus{
+					Groups: []gamev1alpha1.PlaytestGroupStatus{
+						{Name: "Group 1", ServerRef: &corev1.LocalObjectReference{Name: "a"}, Ready: true},
+						{Name: "Group 2", Reason: "ImagePullBackOff"},
+					},
+				},
+			}
+			playtest.SetName("friday")
+
+			archive := newPlaytestArchive(playtest, time.Now())
+			Expect(archive.EndTime.Time).To(Equal(playtest.Spec.StartTime.Add(time.Hour)))
+			Expect(archive.Groups).To(HaveLen(2))
+			Expect(archive.Groups[0].Server).To(Equal("a"))
+			Expect(archive.Groups[0].Version).To(Equal("linux-server-420e4db0"))
+			Expect(archive.Groups[1].Users).To(Equal([]string{"bob"}))
+			Expect(archive.Groups[1].Reason).To(Equal("ImagePullBackOff"))
+		})
+
+		It("should record outcomes as they were when the playtest ended", func() {
+			playtest := &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					Groups: []gamev1alpha1.PlaytestGroup{{Name: "Group 1", Users: []string{"alice"}}},
+				},
+				Status: gamev1alpha1.PlaytestStatus{
+					Groups: []gamev1alpha1.PlaytestGroupStatus{
+						{Name: "Group 1", ServerRef: &corev1.LocalObjectReference{Name: "a"}, Ready: true},
+					},
+				},
+			}
+
+			recordGroupOutcomes(playtest)
+
+			// as teardownGroupServers leaves it
+			playtest.Status.Groups[0].ServerRef = nil
+			playtest.Status.Groups[0].Ready = false
+			recordGroupOutcomes(playtest)
+
+			archive := newPlaytestArchive(playtest, time.Now())
+			Expect(archive.Groups[0].Server).To(Equal("a"))
+			Expect(archive.Groups[0].Ready).To(BeTrue())
+		})
+	})
+
+	Describe("Playtest Pruning", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			r = &PlaytestReconciler{
+				Client:           k8sClient,
+				Scheme:           scheme.Scheme,
+				DefaultRetention: time.Hour,
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "pruned-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:         "linux-server-420e4db0",
+					StartTime:       metav1.NewTime(time.Now().Add(-4 * time.Hour)),
+					Duration:        &metav1.Duration{Duration: time.Hour},
+					MinGroups:       1,
+					PlayersPerGroup: 2,
+					Groups:          []gamev1alpha1.PlaytestGroup{{Name: "Group 1", Users: []string{"alice"}}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", Outcome: &gamev1alpha1.PlaytestGroupOutcome{Server: "pruned-playtest-group-1", Ready: true}},
+			}
+			Expect(k8sClient.Status().Update(ctx, playtest)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, playtest))).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pruned-playtest-archive"},
+			}))).To(Succeed())
+		})
+
+		It("should archive and delete playtests past their retention", func() {
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(playtest)})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), &gamev1alpha1.Playtest{})).ToNot(Succeed())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "pruned-playtest-archive"}, configMap)).To(Succeed())
+			Expect(configMap.GetLabels()).To(HaveKeyWithValue(ArchiveLabel, "true"))
+
+			archive := &playtestArchive{}
+			Expect(json.Unmarshal([]byte(configMap.Data[ArchiveFileName]), archive)).To(Succeed())
+			Expect(archive.Groups).To(HaveLen(1))
+			Expect(archive.Groups[0].Server).To(Equal("pruned-playtest-group-1"))
+			Expect(archive.Groups[0].Ready).To(BeTrue())
+		})
+
+		It("should keep playtests that aren't prunable", func() {
+			before := playtest.DeepCopy()
+			playtest.Annotations = map[string]string{DoNotPruneAnnotation: "true"}
+			Expect(k8sClient.Patch(ctx, playtest, client.MergeFrom(before))).To(Succeed())
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(playtest)})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), &gamev1alpha1.Playtest{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "pruned-playtest-archive"}, &corev1.ConfigMap{})).ToNot(Succeed())
+		})
+
+		It("should leave scheduled playtests to their schedule", func() {
+			before := playtest.DeepCopy()
+			playtest.Labels = map[string]string{PlaytestScheduleLabel: "weekly"}
+			Expect(k8sClient.Patch(ctx, playtest, client.MergeFrom(before))).To(Succeed())
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(playtest)})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), &gamev1alpha1.Playtest{})).To(Succeed())
+		})
+	})
+
+	Describe("Server Names", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			playtest = &gamev1alpha1.Playtest{}
+			playtest.SetName("friday")
+		})
+
+		It("should keep names that are already valid", func() {
+			Expect(groupServerName(playtest, gamev1alpha1.PlaytestGroup{Name: "Group 1"})).To(Equal("friday-group-1"))
+		})
+
+		It("should sanitize punctuation and unicode", func() {
+			name := groupServerName(playtest, gamev1alpha1.PlaytestGroup{Name: "Ü-Boot's Crew!"})
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty())
+			Expect(name).To(HavePrefix("friday-boot-s-crew-"))
+		})
+
+		It("should truncate long names with a stable suffix", func() {
+			group := gamev1alpha1.PlaytestGroup{Name: strings.Repeat("very long group name ", 10)}
+			name := groupServerName(playtest, group)
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty())
+			Expect(groupServerName(playtest, group)).To(Equal(name))
+
+			other := groupServerName(playtest, gamev1alpha1.PlaytestGroup{Name: group.Name + "2"})
+			Expect(other).ToNot(Equal(name))
+		})
+
+		It("should avoid names already taken by another group", func() {
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerName: "friday-group-1"},
+			}
+
+			name := groupServerName(playtest, gamev1alpha1.PlaytestGroup{Name: "group 1"})
+			Expect(name).ToNot(Equal("friday-group-1"))
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty())
+		})
+
+		It("should keep the recorded name", func() {
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerName: "friday-first"},
+				{Name: "Group 2", ServerRef: &corev1.LocalObjectReference{Name: "friday-second"}},
+			}
+
+			Expect(groupServerName(playtest, gamev1alpha1.PlaytestGroup{Name: "Group 1"})).To(Equal("friday-first"))
+			Expect(groupServerName(playtest, gamev1alpha1.PlaytestGroup{Name: "Group 2"})).To(Equal("friday-second"))
+		})
+	})
+
+	Describe("Playtest Actions", func() {
+		var ptr *PlaytestReconciler
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			ptr = &PlaytestReconciler{}
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					StartTime: metav1.NewTime(time.Now().Add(time.Hour)),
+					Duration:  &metav1.Duration{Duration: time.Hour},
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1", Users: []string{"alice", "bob"}},
+						{Name: "Group 2", Users: []string{"carol", "dave"}},
+					},
+				},
+				Status: gamev1alpha1.PlaytestStatus{
+					Assignments: []gamev1alpha1.PlaytestAssignment{
+						{User: "alice", Party: "squad", Group: "Group 1"},
+						{User: "bob", Party: "squad", Group: "Group 1"},
+					},
+				},
+			}
+		})
+
+		It("should carry out actions and clear their annotations", func() {
+			playtest.SetAnnotations(map[string]string{
+				StartNowAnnotation:       "true",
+				ExtendEndAnnotation:      "30",
+				LockMembershipAnnotation: "true",
+				ExtendByAnnotation:       "15",
+			})
+
+			results, pending := ptr.runActions(context.Background(), playtest)
+			Expect(pending).To(BeEmpty())
+			Expect(results).To(HaveLen(3))
+			for _, result := range results {
+				Expect(result.Succeeded).To(BeTrue(), result.Message)
+			}
+
+			Expect(playtest.GetAnnotations()).To(Equal(map[string]string{ExtendByAnnotation: "45"}))
+			Expect(playtest.Spec.StartTime.Time).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(playtest.Spec.LockMembership).To(BeTrue())
+			Expect(playtest.Status.Actions).To(HaveLen(3))
+		})
+
+		It("should record actions that can't be carried out", func() {
+			playtest.SetAnnotations(map[string]string{
+				RestartGroupAnnotation: "Group 9",
+				ExtendEndAnnotation:    "soon",
+			})
+
+			results, pending := ptr.runActions(context.Background(), playtest)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Succeeded).To(BeFalse())
+			Expect(playtest.GetAnnotations()).To(BeEmpty())
+
+			// restarting reaches outside the playtest, so it waits until the annotation's removal is saved
+			Expect(pending).To(HaveLen(1))
+			results = ptr.runPendingActions(context.Background(), playtest, pending)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Action).To(Equal(RestartGroupAnnotation))
+			Expect(results[0].Succeeded).To(BeFalse())
+			Expect(playtest.Status.Actions).To(HaveLen(2))
+		})
+
+		It("should requeue everyone when reshuffling, keeping parties together", func() {
+			playtest.SetAnnotations(map[string]string{ReshuffleAnnotation: ""})
+
+			results, _ := ptr.runActions(context.Background(), playtest)
+			Expect(results[0].Succeeded).To(BeTrue())
+
+			for _, group := range playtest.Spec.Groups {
+				Expect(group.Users).To(BeEmpty())
+			}
+			Expect(playtest.Spec.PartiesToAutoAssign).To(Equal([]gamev1alpha1.PlaytestParty{
+				{Name: "squad", Users: []string{"alice", "bob"}},
+			}))
+			Expect(playtest.Spec.UsersToAutoAssign).To(ConsistOf("carol", "dave"))
+		})
+
+		It("should not reshuffle locked membership", func() {
+			playtest.Spec.LockMembership = true
+			playtest.SetAnnotations(map[string]string{ReshuffleAnnotation: ""})
+
+			results, _ := ptr.runActions(context.Background(), playtest)
+			Expect(results[0].Succeeded).To(BeFalse())
+			Expect(playtest.Spec.Groups[0].Users).To(HaveLen(2))
+		})
+	})
+
+	Describe("Late Joiners", func() {
+		var ptr *PlaytestReconciler
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			ptr = &PlaytestReconciler{}
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					MinGroups:          3,
+					PlayersPerGroup:    4,
+					StartTime:          metav1.NewTime(time.Now().Add(-time.Minute)),
+					AssignmentStrategy: gamev1alpha1.AssignmentStrategyLeastFilled,
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1", Users: []string{"alice"}},
+						{Name: "Group 2", Users: []string{"bob", "carol"}},
+						{Name: "Group 3", Users: []string{"dave", "erin"}},
+					},
+				},
+				Status: gamev1alpha1.PlaytestStatus{
+					Groups: []gamev1alpha1.PlaytestGroupStatus{
+						{Name: "Group 1", Players: pointer.Int32(4), ServerRef: &corev1.LocalObjectReference{Name: "friday-group-1"}},
+						{Name: "Group 2", AcceptingJoiners: pointer.Bool(false), ServerRef: &corev1.LocalObjectReference{Name: "friday-group-2"}},
+						{Name: "Group 3", ServerRef: &corev1.LocalObjectReference{Name: "friday-group-3"}, Address: "10.0.0.3:7777"},
+					},
+				},
+			}
+		})
+
+		It("should place late joiners on servers with room that take joiners", func() {
+			playtest.Spec.UsersToAutoAssign = []string{"frank"}
+			ptr.assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Spec.Groups[2].Users).To(ContainElement("frank"))
+		})
+
+		It("should fall back to servers that aren't taking joiners", func() {
+			playtest.Status.Groups[2].AcceptingJoiners = pointer.Bool(false)
+			playtest.Spec.UsersToAutoAssign = []string{"frank"}
+			ptr.assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Spec.Groups[1].Users).To(ContainElement("frank"))
+		})
+
+		It("should report where late joiners connect", func() {
+			playtest.Spec.UsersToAutoAssign = []string{"frank"}
+			ptr.assignQueued(context.Background(), playtest)
+			updateLateJoiners(playtest)
+
+			Expect(playtest.Status.LateJoiners).To(HaveLen(1))
+			Expect(playtest.Status.LateJoiners[0].User).To(Equal("frank"))
+			Expect(playtest.Status.LateJoiners[0].Server).To(Equal("friday-group-3"))
+			Expect(playtest.Status.LateJoiners[0].Address).To(Equal("10.0.0.3:7777"))
+		})
+
+		It("should not count live players before the start", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(time.Hour))
+			playtest.Spec.UsersToAutoAssign = []string{"frank"}
+			ptr.assignQueued(context.Background(), playtest)
+
+			Expect(playtest.Spec.Groups[0].Users).To(ContainElement("frank"))
+			Expect(playtest.Status.LateJoiners).To(BeEmpty())
+		})
+	})
+
+	Describe("Group Rebalancing", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					PlayersPerGroup: 4,
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1", Users: []string{"alice", "bob", "carol", "dave"}},
+						{Name: "Group 2", Users: []string{"erin"}},
+						{Name: "Group 3"},
+					},
+				},
+			}
+		})
+
+		It("should even out group sizes", func() {
+			moves := rebalanceGroups(playtest)
+			Expect(moves).To(HaveLen(2))
+
+			for _, group := range playtest.Spec.Groups {
+				Expect(len(group.Users)).To(BeNumerically(">=", 1))
+				Expect(len(group.Users)).To(BeNumerically("<=", 2))
+			}
+		})
+
+		It("should move parties as a whole", func() {
+			playtest.Spec.Groups[1].Users = nil
+			playtest.Status.Assignments = []gamev1alpha1.PlaytestAssignment{
+				{User: "alice", Party: "squad", Group: "Group 1"},
+				{User: "bob", Party: "squad", Group: "Group 1"},
+			}
+
+			moves := rebalanceGroups(playtest)
+			Expect(moves).To(ContainElements(
+				gamev1alpha1.PlaytestGroupMove{User: "alice", Party: "squad", From: "Group 1", To: "Group 3"},
+				gamev1alpha1.PlaytestGroupMove{User: "bob", Party: "squad", From: "Group 1", To: "Group 3"},
+			))
+			Expect(playtest.Spec.Groups[2].Users).To(Equal([]string{"alice", "bob"}))
+		})
+
+		It("should record a rebalance in status", func() {
+			playtest.SetAnnotations(map[string]string{RebalanceAnnotation: ""})
+
+			results, _ := (&PlaytestReconciler{}).runActions(context.Background(), playtest)
+			Expect(results[0].Succeeded).To(BeTrue())
+			Expect(playtest.Status.LastRebalance).ToNot(BeNil())
+			Expect(playtest.Status.LastRebalance.Moves).To(HaveLen(2))
+		})
+	})
+
+	Describe("Server Changes", func() {
+		var playtest *gamev1alpha1.Playtest
+		var group gamev1alpha1.PlaytestGroup
+
+		BeforeEach(func() {
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					StartTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+				},
+			}
+			group = gamev1alpha1.PlaytestGroup{Name: "Group 1", Users: []string{"alice"}}
+		})
+
+		It("should apply changes right away before the start", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(time.Hour))
+			playtest.Spec.ServerChangePolicy = gamev1alpha1.ServerChangePolicyBlock
+			Expect(serverChangeState(playtest, group, time.Now())).To(BeEmpty())
+		})
+
+		It("should wait for the group to empty by default", func() {
+			Expect(serverChangeState(playtest, group, time.Now())).To(Equal(gamev1alpha1.ServerChangeWaitingForEmpty))
+
+			group.Users = nil
+			Expect(serverChangeState(playtest, group, time.Now())).To(BeEmpty())
+		})
+
+		It("should go by the players the server reports when it does", func() {
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{{Name: "Group 1", Players: pointer.Int32(0)}}
+			Expect(serverChangeState(playtest, group, time.Now())).To(BeEmpty())
+
+			group.Users = nil
+			playtest.Status.Groups[0].Players = pointer.Int32(1)
+			Expect(serverChangeState(playtest, group, time.Now())).To(Equal(gamev1alpha1.ServerChangeWaitingForEmpty))
+		})
+
+		It("should block changes", func() {
+			playtest.Spec.ServerChangePolicy = gamev1alpha1.ServerChangePolicyBlock
+			group.Users = nil
+			Expect(serverChangeState(playtest, group, time.Now())).To(Equal(gamev1alpha1.ServerChangeBlocked))
+		})
+
+		It("should apply immediately once confirmed", func() {
+			playtest.Spec.ServerChangePolicy = gamev1alpha1.ServerChangePolicyImmediate
+			Expect(serverChangeState(playtest, group, time.Now())).To(Equal(gamev1alpha1.ServerChangeWaitingForConfirmation))
+
+			playtest.SetAnnotations(map[string]string{ConfirmServerChangeAnnotation: "true"})
+			Expect(serverChangeState(playtest, group, time.Now())).To(BeEmpty())
+		})
+	})
+
+	Describe("Spare Servers", func() {
+		It("should treat crashing and failed pods as failed", func() {
+			gameServer := &gamev1alpha1.GameServer{}
+			Expect(groupServerFailed(gameServer)).To(BeFalse())
+
+			gameServer.Status.PodStatus = &corev1.PodStatus{Phase: corev1.PodRunning}
+			Expect(groupServerFailed(gameServer)).To(BeFalse())
+
+			gameServer.Status.PodStatus.ContainerStatuses = []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+			}
+			Expect(groupServerFailed(gameServer)).To(BeTrue())
+
+			gameServer.Status.PodStatus = &corev1.PodStatus{Phase: corev1.PodFailed}
+			Expect(groupServerFailed(gameServer)).To(BeTrue())
+		})
+
+		It("should treat servers that ran out of reschedule attempts as failed", func() {
+			gameServer := &gamev1alpha1.GameServer{}
+			conditions.MarkFalse(gameServer, gamev1alpha1.PodScheduledCondition, gamev1alpha1.RescheduleAttemptsExceededReason, clusterv1.ConditionSeverityError, "")
+			Expect(groupServerFailed(gameServer)).To(BeTrue())
+		})
+	})
+
+	Describe("Orphaned Servers", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		newGroupServer := func(name string) *gamev1alpha1.GameServer {
+			gameServer := &gamev1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      name,
+					Labels: map[string]string{
+						PlaytestLabel: playtest.GetName(),
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: gamev1alpha1.GroupVersion.String(),
+							Kind:       "Playtest",
+							Name:       playtest.GetName(),
+							UID:        playtest.GetUID(),
+							Controller: pointer.Bool(true),
+						},
+					},
+				},
+				Spec: gamev1alpha1.GameServerSpec{
+					Version: "linux-server-420e4db0",
+					Map:     "/Game/Maps/Test",
+				},
+			}
+			Expect(k8sClient.Create(ctx, gameServer)).To(Succeed())
+
+			return gameServer
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			r = &PlaytestReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "orphan-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					MinGroups:       1,
+					PlayersPerGroup: 2,
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerRef: &corev1.LocalObjectReference{Name: "orphan-playtest-group-1"}},
+				{Name: "Group 2", ServerRef: &corev1.LocalObjectReference{Name: "orphan-playtest-group-2"}},
+			}
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &gamev1alpha1.GameServer{}, client.InNamespace("default"),
+				client.MatchingLabels{PlaytestLabel: playtest.GetName()})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, playtest)).To(Succeed())
+		})
+
+		It("should delete servers of removed groups and keep the rest", func() {
+			newGroupServer("orphan-playtest-group-1")
+			newGroupServer("orphan-playtest-group-2")
+
+			requeueAfter, err := r.collectOrphanedServers(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requeueAfter).To(BeZero())
+
+			Expect(playtest.Status.Groups).To(HaveLen(1))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "orphan-playtest-group-1"}, &gamev1alpha1.GameServer{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "orphan-playtest-group-2"}, &gamev1alpha1.GameServer{})).ToNot(Succeed())
+		})
+
+		It("should drain orphaned servers for the teardown grace period", func() {
+			playtest.Spec.TeardownGracePeriod = &metav1.Duration{Duration: 5 * time.Minute}
+			newGroupServer("orphan-playtest-group-2")
+
+			requeueAfter, err := r.collectOrphanedServers(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requeueAfter).To(BeNumerically("~", 5*time.Minute, time.Minute))
+
+			gameServer := &gamev1alpha1.GameServer{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "orphan-playtest-group-2"}, gameServer)).To(Succeed())
+			Expect(gameServer.GetAnnotations()).To(HaveKey(DrainingAnnotation))
+		})
+
+		It("should leave spares alone", func() {
+			spare := newGroupServer("orphan-playtest-spare-1")
+			before := spare.DeepCopy()
+			spare.Labels[SpareLabel] = "true"
+			Expect(k8sClient.Patch(ctx, spare, client.MergeFrom(before))).To(Succeed())
+
+			_, err := r.collectOrphanedServers(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "orphan-playtest-spare-1"}, &gamev1alpha1.GameServer{})).To(Succeed())
+		})
+
+		It("should leave servers it doesn't control alone", func() {
+			gameServer := newGroupServer("orphan-playtest-group-2")
+			before := gameServer.DeepCopy()
+			gameServer.OwnerReferences = nil
+			Expect(k8sClient.Patch(ctx, gameServer, client.MergeFrom(before))).To(Succeed())
+
+			_, err := r.collectOrphanedServers(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "orphan-playtest-group-2"}, &gamev1alpha1.GameServer{})).To(Succeed())
+		})
+	})
+
+	Describe("Membership Conflicts", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		fetch := func() *gamev1alpha1.Playtest {
+			fetched := &gamev1alpha1.Playtest{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), fetched)).To(Succeed())
+
+			return fetched
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			r = &PlaytestReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme.Scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "conflict-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					StartTime:         metav1.NewTime(time.Now().Add(time.Hour)),
+					MinGroups:         1,
+					PlayersPerGroup:   1,
+					Groups:            []gamev1alpha1.PlaytestGroup{{Name: "Group 1", Users: []string{"alice"}}},
+					UsersToAutoAssign: []string{"bob"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, playtest)).To(Succeed())
+		})
+
+		It("should save nothing when the playtest changed, and waitlist once on retry", func() {
+			stale := fetch()
+
+			edited := fetch()
+			before := edited.DeepCopy()
+			edited.Spec.PlayersPerGroup = 2
+			edited.Spec.Groups[0].Users = []string{"alice", "carol"}
+			Expect(k8sClient.Patch(ctx, edited, client.MergeFrom(before))).To(Succeed())
+
+			_, err := r.reconcileMembership(ctx, stale)
+			Expect(apierrors.IsConflict(err)).To(BeTrue())
+
+			saved := fetch()
+			Expect(saved.Spec.UsersToAutoAssign).To(Equal([]string{"bob"}))
+			Expect(saved.Spec.Groups[0].Users).To(Equal([]string{"alice", "carol"}))
+			Expect(saved.Status.Waitlist).To(BeEmpty())
+
+			retried := fetch()
+			_, err = r.reconcileMembership(ctx, retried)
+			Expect(err).ToNot(HaveOccurred())
+
+			saved = fetch()
+			Expect(saved.Spec.UsersToAutoAssign).To(BeEmpty())
+			Expect(saved.Spec.Groups[0].Users).To(Equal([]string{"alice", "carol"}))
+			Expect(saved.Status.Waitlist).To(HaveLen(1))
+			Expect(saved.Status.Waitlist[0].Users).To(Equal([]string{"bob"}))
+		})
+
+		It("should only restart a group's server once its action is saved", func() {
+			gameServer := &gamev1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "conflict-playtest-group-1",
+				},
+				Spec: gamev1alpha1.GameServerSpec{
+					Version: "linux-server-420e4db0",
+				},
+			}
+			Expect(k8sClient.Create(ctx, gameServer)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, gameServer)).To(Succeed())
+			}()
+
+			annotated := fetch()
+			before := annotated.DeepCopy()
+			annotated.Annotations = map[string]string{RestartGroupAnnotation: "Group 1"}
+			Expect(k8sClient.Patch(ctx, annotated, client.MergeFrom(before))).To(Succeed())
+			annotated.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerRef: &corev1.LocalObjectReference{Name: gameServer.GetName()}},
+			}
+			Expect(k8sClient.Status().Update(ctx, annotated)).To(Succeed())
+
+			stale := fetch()
+			edited := fetch()
+			before = edited.DeepCopy()
+			edited.Spec.Groups[0].Users = []string{"alice", "carol"}
+			Expect(k8sClient.Patch(ctx, edited, client.MergeFrom(before))).To(Succeed())
+
+			_, err := r.reconcileMembership(ctx, stale)
+			Expect(apierrors.IsConflict(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(gameServer), gameServer)).To(Succeed())
+			Expect(gameServer.GetAnnotations()).ToNot(HaveKey(RestartAnnotation))
+
+			membership, err := r.reconcileMembership(ctx, fetch())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(membership.Status.Actions).To(HaveLen(1))
+			Expect(membership.Status.Actions[0].Succeeded).To(BeTrue())
+			Expect(fetch().GetAnnotations()).ToNot(HaveKey(RestartGroupAnnotation))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(gameServer), gameServer)).To(Succeed())
+			Expect(gameServer.GetAnnotations()).To(HaveKey(RestartAnnotation))
+		})
+
+		It("should pick up users that were waitlisted but not taken off the queue", func() {
+			waitlisted := fetch()
+			addToWaitlist(waitlisted, "", []string{"bob"}, "no open groups", time.Now())
+			Expect(k8sClient.Status().Update(ctx, waitlisted)).To(Succeed())
+
+			membership, err := r.reconcileMembership(ctx, fetch())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(membership.Status.Waitlist).To(HaveLen(1))
+
+			saved := fetch()
+			Expect(saved.Spec.UsersToAutoAssign).To(BeEmpty())
+			Expect(saved.Status.Waitlist).To(HaveLen(1))
+		})
+	})
+
+	Describe("Live Server Changes", func() {
+		var playtest *gamev1alpha1.Playtest
+		var gameServer *gamev1alpha1.GameServer
+
+		reconcileGroup := func(players int32) {
+			gameServer.Status.Players = pointer.Int32(players)
+			Expect(k8sClient.Status().Update(ctx, gameServer)).To(Succeed())
+
+			_, err := r.reconcileGroupServer(ctx, playtest, playtest.Spec.Groups[0], 1, &provisioningBatch{remaining: -1})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			r = &PlaytestReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "changing-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:         "1111aaaa",
+					StartTime:       metav1.NewTime(time.Now().Add(-time.Minute)),
+					MinGroups:       1,
+					PlayersPerGroup: 2,
+					Groups:          []gamev1alpha1.PlaytestGroup{{Name: "Group 1", Users: []string{"alice"}}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+
+			gameServer = &gamev1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "changing-playtest-group-1",
+					Labels: map[string]string{
+						PlaytestLabel: playtest.GetName(),
+					},
+				},
+				Spec: gamev1alpha1.GameServerSpec{
+					Version: "linux-server-0000ffff",
+				},
+			}
+			Expect(k8sClient.Create(ctx, gameServer)).To(Succeed())
+
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerName: gameServer.GetName(), ServerRef: &corev1.LocalObjectReference{Name: gameServer.GetName()}},
+			}
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &gamev1alpha1.GameServer{}, client.InNamespace("default"),
+				client.MatchingLabels{PlaytestLabel: playtest.GetName()})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, playtest)).To(Succeed())
+		})
+
+		It("should keep the server while players are connected", func() {
+			reconcileGroup(1)
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(gameServer), &gamev1alpha1.GameServer{})).To(Succeed())
+			Expect(playtest.Status.Groups[0].ServerChange).To(Equal(gamev1alpha1.ServerChangeWaitingForEmpty))
+		})
+
+		It("should replace the server once it's empty, even with users assigned", func() {
+			reconcileGroup(0)
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(gameServer), &gamev1alpha1.GameServer{})).ToNot(Succeed())
+			Expect(playtest.Status.Groups[0].ServerRef).To(BeNil())
+		})
+	})
+
+	Describe("Group Allowlists", func() {
+		var playtest *gamev1alpha1.Playtest
+		var gsr *GameServerReconciler
+		var configMap *corev1.ConfigMap
+
+		// allowedUsers reconciles the group's server and its allowlist, and returns who the allowlist lets in
+		allowedUsers := func() []string {
+			_, err := r.reconcileGroupServer(ctx, playtest, playtest.Spec.Groups[0], 1, &provisioningBatch{remaining: -1})
+			Expect(err).ToNot(HaveOccurred())
+
+			gameServer := &gamev1alpha1.GameServer{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: playtest.Status.Groups[0].ServerRef.Name}, gameServer)).To(Succeed())
+			Expect(gsr.reconcileAllowlist(ctx, gameServer)).To(Succeed())
+
+			configMap = &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: allowlistConfigMapName(gameServer)}, configMap)).To(Succeed())
+
+			list := allowlist{}
+			Expect(json.Unmarshal([]byte(configMap.Data[AllowlistFileName]), &list)).To(Succeed())
+
+			users := []string{}
+			for _, entry := range list.Users {
+				users = append(users, entry.Name)
+			}
+
+			return users
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			r = &PlaytestReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+			gsr = &GameServerReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "allowlist-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:         "1111aaaa",
+					StartTime:       metav1.NewTime(time.Now().Add(-time.Minute)),
+					MinGroups:       1,
+					PlayersPerGroup: 3,
+					Groups:          []gamev1alpha1.PlaytestGroup{{Name: "Group 1", Users: []string{"alice", "bob"}}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			// there's no garbage collector to follow the ConfigMap's owner
+			Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &gamev1alpha1.GameServer{}, client.InNamespace("default"),
+				client.MatchingLabels{PlaytestLabel: playtest.GetName()})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, playtest)).To(Succeed())
+		})
+
+		It("should list the group's users", func() {
+			Expect(allowedUsers()).To(Equal([]string{"alice", "bob"}))
+		})
+
+		It("should follow group membership changes", func() {
+			Expect(allowedUsers()).To(Equal([]string{"alice", "bob"}))
+
+			playtest.Spec.Groups[0].Users = []string{"bob", "carol"}
+			Expect(allowedUsers()).To(Equal([]string{"bob", "carol"}))
+
+			playtest.Spec.Groups[0].Users = nil
+			Expect(allowedUsers()).To(BeEmpty())
+		})
+	})
+
+	Describe("Spare Failover", func() {
+		var playtest *gamev1alpha1.Playtest
+		var recorder *record.FakeRecorder
+
+		newReadySpare := func() *gamev1alpha1.GameServer {
+			before, err := r.listSpares(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.reconcileSpares(ctx, playtest, len(before)+1, &provisioningBatch{remaining: -1})).To(Succeed())
+
+			spares, err := r.listSpares(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			for i := range spares {
+				spare := &spares[i]
+				if !spare.Status.Ready {
+					spare.Status.Ready = true
+					Expect(k8sClient.Status().Update(ctx, spare)).To(Succeed())
+
+					return spare
+				}
+			}
+
+			Fail("no new spare was created")
+			return nil
+		}
+
+		newFailedServer := func(name string) *gamev1alpha1.GameServer {
+			gameServer := &gamev1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      name,
+					Labels: map[string]string{
+						PlaytestLabel: playtest.GetName(),
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: gamev1alpha1.GroupVersion.String(),
+							Kind:       "Playtest",
+							Name:       playtest.GetName(),
+							UID:        playtest.GetUID(),
+							Controller: pointer.Bool(true),
+						},
+					},
+				},
+				Spec: gamev1alpha1.GameServerSpec{
+					Version:  "linux-server-420e4db0",
+					Map:      "/Game/Maps/Test",
+					Playtest: &gamev1alpha1.GameServerPlaytest{Name: playtest.GetName(), Group: "Group 1", GroupIndex: 1},
+				},
+			}
+			Expect(k8sClient.Create(ctx, gameServer)).To(Succeed())
+
+			gameServer.Status.PodStatus = &corev1.PodStatus{Phase: corev1.PodFailed}
+			Expect(k8sClient.Status().Update(ctx, gameServer)).To(Succeed())
+
+			return gameServer
+		}
+
+		failover := func() (bool, error) {
+			group := playtest.Spec.Groups[0]
+
+			return r.failoverGroupServer(ctx, playtest, group, &playtest.Status.Groups[0], resolveGroupServer(playtest, group), &gamev1alpha1.GameServerPlaytest{
+				Name:       playtest.GetName(),
+				Group:      group.Name,
+				GroupIndex: 1,
+				Users:      group.Users,
+			})
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			recorder = record.NewFakeRecorder(10)
+
+			r = &PlaytestReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme.Scheme,
+				Recorder: recorder,
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "spare-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:         "420e4db0",
+					Map:             "/Game/Maps/Test",
+					MinGroups:       1,
+					PlayersPerGroup: 2,
+					Spares:          1,
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1", Users: []string{"alice"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerRef: &corev1.LocalObjectReference{Name: "spare-playtest-group-1"}},
+			}
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &gamev1alpha1.GameServer{}, client.InNamespace("default"),
+				client.MatchingLabels{PlaytestLabel: playtest.GetName()})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, playtest)).To(Succeed())
+		})
+
+		It("should keep spares on the playtest's version, map and args", func() {
+			spare := newReadySpare()
+			Expect(spare.GetLabels()).To(HaveKeyWithValue(SpareLabel, "true"))
+			Expect(spare.Spec.Version).To(Equal("linux-server-420e4db0"))
+			Expect(spare.Spec.Playtest).To(Equal(&gamev1alpha1.GameServerPlaytest{Name: playtest.GetName()}))
+
+			playtest.Spec.GameServerCmdArgs = []string{"-log"}
+			Expect(r.reconcileSpares(ctx, playtest, 1, &provisioningBatch{remaining: -1})).To(Succeed())
+
+			spares, err := r.listSpares(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spares).To(HaveLen(1))
+			Expect(spares[0].GetName()).ToNot(Equal(spare.GetName()))
+			Expect(spares[0].Spec.CmdArgs).To(Equal([]string{"-log"}))
+		})
+
+		It("should swap a failed group server for a spare", func() {
+			newFailedServer("spare-playtest-group-1")
+			spare := newReadySpare()
+
+			swapped, err := failover()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(swapped).To(BeTrue())
+			Expect(playtest.Status.Groups[0].ServerRef.Name).To(Equal(spare.GetName()))
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "spare-playtest-group-1"}, &gamev1alpha1.GameServer{})).ToNot(Succeed())
+
+			claimed := &gamev1alpha1.GameServer{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(spare), claimed)).To(Succeed())
+			Expect(claimed.GetLabels()).ToNot(HaveKey(SpareLabel))
+			Expect(claimed.Spec.Playtest.Group).To(Equal("Group 1"))
+			Expect(claimed.Spec.Playtest.Users).To(Equal([]string{"alice"}))
+			Expect(claimed.GetAnnotations()).ToNot(HaveKey(RestartAnnotation))
+
+			Expect(recorder.Events).To(Receive(ContainSubstring(GroupServerFailoverReason)))
+		})
+
+		It("should not fail over without a matching spare", func() {
+			newFailedServer("spare-playtest-group-1")
+			newReadySpare()
+			playtest.Spec.Groups[0].GameServerCmdArgs = []string{"-group-only"}
+
+			swapped, err := failover()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(swapped).To(BeFalse())
+			Expect(playtest.Status.Groups[0].ServerRef.Name).To(Equal("spare-playtest-group-1"))
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("should restart a claimed spare whose args depend on the group", func() {
+			playtest.Spec.GameServerCmdArgs = []string{"-SessionName={{ .Playtest }}-{{ .GroupIndex }}"}
+			newFailedServer("spare-playtest-group-1")
+			spare := newReadySpare()
+
+			swapped, err := failover()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(swapped).To(BeTrue())
+
+			claimed := &gamev1alpha1.GameServer{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(spare), claimed)).To(Succeed())
+			Expect(claimed.GetAnnotations()).To(HaveKey(RestartAnnotation))
+		})
+
+		It("should keep a claimed spare when the claim wasn't recorded", func() {
+			newFailedServer("spare-playtest-group-1")
+			spare := newReadySpare()
+
+			swapped, err := failover()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(swapped).To(BeTrue())
+
+			// the status update was lost, so the group still points at the failed server
+			playtest.Status.Groups[0].ServerRef = &corev1.LocalObjectReference{Name: "spare-playtest-group-1"}
+			other := newReadySpare()
+
+			_, err = r.collectOrphanedServers(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(spare), &gamev1alpha1.GameServer{})).To(Succeed())
+
+			swapped, err = failover()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(swapped).To(BeTrue())
+			Expect(playtest.Status.Groups[0].ServerRef.Name).To(Equal(spare.GetName()))
+
+			unclaimed := &gamev1alpha1.GameServer{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(other), unclaimed)).To(Succeed())
+			Expect(unclaimed.GetLabels()).To(HaveKeyWithValue(SpareLabel, "true"))
+		})
+	})
+
+	Describe("Scheduled Requeues", func() {
+		var playtest *gamev1alpha1.Playtest
+		var now time.Time
+
+		BeforeEach(func() {
+			r = &PlaytestReconciler{
+				DefaultRetention: 24 * time.Hour,
+			}
+
+			now = time.Now()
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					StartTime: metav1.NewTime(now.Add(time.Hour)),
+					Duration:  &metav1.Duration{Duration: 2 * time.Hour},
+				},
+			}
+		})
+
+		It("should pick the smallest positive delay", func() {
+			Expect(soonest(0, 0)).To(BeZero())
+			Expect(soonest(time.Minute, 0)).To(Equal(time.Minute))
+			Expect(soonest(0, time.Minute)).To(Equal(time.Minute))
+			Expect(soonest(-time.Minute, time.Hour)).To(Equal(time.Hour))
+			Expect(soonest(time.Hour, time.Minute)).To(Equal(time.Minute))
+		})
+
+		It("should come back to create servers", func() {
+			Expect(r.untilNextTransition(playtest, now)).To(Equal(time.Hour - defaultProvisioningLeadTime))
+		})
+
+		It("should come back at the start", func() {
+			Expect(r.untilNextTransition(playtest, now.Add(55*time.Minute))).To(Equal(5 * time.Minute))
+		})
+
+		It("should come back at the end", func() {
+			Expect(r.untilNextTransition(playtest, now.Add(2*time.Hour))).To(Equal(time.Hour))
+		})
+
+		It("should come back once the retention is up", func() {
+			Expect(r.untilNextTransition(playtest, now.Add(4*time.Hour))).To(Equal(23 * time.Hour))
+		})
+
+		It("should not come back to prune a playtest that's kept", func() {
+			playtest.Annotations = map[string]string{DoNotPruneAnnotation: "true"}
+			Expect(r.untilNextTransition(playtest, now.Add(4*time.Hour))).To(BeZero())
+		})
+	})
+
+	Describe("Playtest Phase", func() {
+		var playtest *gamev1alpha1.Playtest
+
+		BeforeEach(func() {
+			playtest = &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					MinGroups:       2,
+					PlayersPerGroup: 2,
+					Groups: []gamev1alpha1.PlaytestGroup{
+						{Name: "Group 1"},
+						{Name: "Group 2"},
+					},
+				},
+			}
+		})
+
+		It("should be scheduled when the start is far out", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(time.Hour))
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseScheduled))
+			Expect(conditions.GetReason(playtest, gamev1alpha1.ServersProvisionedCondition)).To(Equal(gamev1alpha1.WaitingForProvisioningReason))
+			Expect(playtest.Status.PhaseTransitions).To(HaveLen(1))
+		})
+
+		It("should be ready when every group server is ready", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(5 * time.Minute))
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerRef: &corev1.LocalObjectReference{Name: "a"}, Ready: true},
+				{Name: "Group 2", ServerRef: &corev1.LocalObjectReference{Name: "b"}, Ready: true},
+			}
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseReady))
+			Expect(conditions.IsTrue(playtest, gamev1alpha1.ServersProvisionedCondition)).To(BeTrue())
+			Expect(conditions.IsTrue(playtest, gamev1alpha1.AllGroupsReadyCondition)).To(BeTrue())
+		})
+
+		It("should fail when a group server can't pull its image", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-5 * time.Minute))
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerRef: &corev1.LocalObjectReference{Name: "a"}, Ready: true},
+				{Name: "Group 2", ServerRef: &corev1.LocalObjectReference{Name: "b"}, Reason: "ImagePullBackOff"},
+			}
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseFailed))
+		})
+
+		It("should record the latest transition into each phase", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-5 * time.Minute))
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseInProgress))
+
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-48 * time.Hour))
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseEnded))
+			Expect(playtest.Status.PhaseTransitions).To(HaveLen(2))
+		})
+
+		It("should end after its duration", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			playtest.Spec.Duration = &metav1.Duration{Duration: 90 * time.Minute}
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseEnded))
+		})
+
+		It("should push the end back when extended", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			playtest.Spec.EndTime = &metav1.Time{Time: playtest.Spec.StartTime.Add(90 * time.Minute)}
+			playtest.SetAnnotations(map[string]string{ExtendByAnnotation: "60"})
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseInProgress))
+		})
+
+		It("should be suspended even after it ends", func() {
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			playtest.Spec.Duration = &metav1.Duration{Duration: time.Hour}
+			playtest.Spec.Suspend = true
+			setPlaytestPhase(playtest, defaultProvisioningLeadTime)
+
+			Expect(playtest.Status.Phase).To(Equal(gamev1alpha1.PlaytestPhaseSuspended))
+		})
+	})
+
+	Describe("Suspended Playtests", func() {
+		It("should not auto-assign users", func() {
+			playtest := &gamev1alpha1.Playtest{
+				Spec: gamev1alpha1.PlaytestSpec{
+					MinGroups:         1,
+					PlayersPerGroup:   2,
+					StartTime:         metav1.NewTime(time.Now().Add(time.Hour)),
+					Groups:            []gamev1alpha1.PlaytestGroup{{Name: "Group 1"}},
+					UsersToAutoAssign: []string{"alice"},
+					Suspend:           true,
+				},
+			}
+
+			membership, err := (&PlaytestReconciler{}).reconcileMembership(context.Background(), playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(membership.Spec.Groups[0].Users).To(BeEmpty())
+			Expect(membership.Spec.UsersToAutoAssign).To(Equal([]string{"alice"}))
+		})
+	})
+
+	Describe("Suspended Playtest Servers", func() {
+		var playtest *gamev1alpha1.Playtest
+		var gameServer *gamev1alpha1.GameServer
+
+		listServers := func() []gamev1alpha1.GameServer {
+			gameServers := &gamev1alpha1.GameServerList{}
+			Expect(k8sClient.List(ctx, gameServers, client.InNamespace("default"), client.MatchingLabels{PlaytestLabel: playtest.GetName()})).To(Succeed())
+
+			return gameServers.Items
+		}
+
+		// createGroupServer creates a server for the group as if it was provisioned before the playtest was suspended
+		createGroupServer := func(annotations map[string]string) {
+			gameServer = &gamev1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "default",
+					Name:        "suspended-playtest-group-1",
+					Labels:      map[string]string{PlaytestLabel: playtest.GetName()},
+					Annotations: annotations,
+				},
+				Spec: gamev1alpha1.GameServerSpec{
+					Version: "linux-server-1111aaaa",
+				},
+			}
+			Expect(k8sClient.Create(ctx, gameServer)).To(Succeed())
+
+			playtest.Status.Groups = []gamev1alpha1.PlaytestGroupStatus{
+				{Name: "Group 1", ServerName: gameServer.GetName(), ServerRef: &corev1.LocalObjectReference{Name: gameServer.GetName()}},
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			r = &PlaytestReconciler{
+				Client:           k8sClient,
+				Scheme:           scheme.Scheme,
+				DefaultRetention: time.Hour,
+			}
+
+			playtest = &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "suspended-playtest",
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:             "1111aaaa",
+					StartTime:           metav1.NewTime(time.Now().Add(-time.Minute)),
+					MinGroups:           1,
+					PlayersPerGroup:     2,
+					Groups:              []gamev1alpha1.PlaytestGroup{{Name: "Group 1", Users: []string{"alice"}}},
+					Suspend:             true,
+					TeardownGracePeriod: &metav1.Duration{Duration: time.Hour},
+				},
+			}
+			Expect(k8sClient.Create(ctx, playtest)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &gamev1alpha1.GameServer{}, client.InNamespace("default"),
+				client.MatchingLabels{PlaytestLabel: playtest.GetName()})).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, playtest))).To(Succeed())
+		})
+
+		It("should not create servers", func() {
+			_, err := r.reconcilePlaytest(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(listServers()).To(BeEmpty())
+		})
+
+		It("should keep existing servers running", func() {
+			createGroupServer(nil)
+
+			_, err := r.reconcilePlaytest(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+
+			servers := listServers()
+			Expect(servers).To(HaveLen(1))
+			Expect(servers[0].GetAnnotations()).ToNot(HaveKey(DrainingAnnotation))
+			Expect(playtest.Status.Groups[0].ServerRef).ToNot(BeNil())
+		})
+
+		It("should drain and then delete servers with drainOnSuspend", func() {
+			playtest.Spec.DrainOnSuspend = true
+			createGroupServer(nil)
+
+			result, err := r.reconcilePlaytest(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+			servers := listServers()
+			Expect(servers).To(HaveLen(1))
+			Expect(servers[0].GetAnnotations()).To(HaveKey(DrainingAnnotation))
+
+			// once the grace period is up
+			playtest.Spec.TeardownGracePeriod = &metav1.Duration{Duration: 0}
+			_, err = r.reconcilePlaytest(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(listServers()).To(BeEmpty())
+			Expect(playtest.Status.Groups[0].ServerRef).To(BeNil())
+		})
+
+		It("should replace servers still draining when resumed", func() {
+			createGroupServer(map[string]string{DrainingAnnotation: time.Now().UTC().Format(time.RFC3339)})
+			drained := gameServer.GetUID()
+
+			playtest.Spec.Suspend = false
+			_, err := r.reconcilePlaytest(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(playtest.Status.Groups[0].ServerRef).To(BeNil())
+
+			_, err = r.reconcilePlaytest(ctx, playtest)
+			Expect(err).ToNot(HaveOccurred())
+
+			servers := listServers()
+			Expect(servers).To(HaveLen(1))
+			Expect(servers[0].GetUID()).ToNot(Equal(drained))
+			Expect(servers[0].GetAnnotations()).ToNot(HaveKey(DrainingAnnotation))
+		})
+
+		It("should not be pruned", func() {
+			before := playtest.DeepCopy()
+			playtest.Spec.StartTime = metav1.NewTime(time.Now().Add(-4 * time.Hour))
+			playtest.Spec.Duration = &metav1.Duration{Duration: time.Hour}
+			Expect(k8sClient.Patch(ctx, playtest, client.MergeFrom(before))).To(Succeed())
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(playtest)})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(playtest), &gamev1alpha1.Playtest{})).To(Succeed())
+		})
+	})
 })
@@ -0,0 +1,164 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+const (
+	// defaultProvisioningLeadTime is how long before the start time group servers are created, if neither
+	// the playtest nor the operator configure it
+	defaultProvisioningLeadTime = 10 * time.Minute
+
+	// playtestLifetime is how long after the start time a playtest without an end time is considered over
+	playtestLifetime = 24 * time.Hour
+)
+
+// PlaytestLabel is set on a playtest's GameServers to the playtest's name
+const PlaytestLabel = "believer.dev/playtest"
+
+// ExtendByAnnotation pushes a playtest's end time back by the given number of minutes
+const ExtendByAnnotation = "believer.dev/extend-by"
+
+// playtestEndTime returns when the playtest is over
+func playtestEndTime(playtest *gamev1alpha1.Playtest) time.Time {
+	end := playtest.Spec.StartTime.Add(playtestLifetime)
+	if playtest.Spec.EndTime != nil {
+		end = playtest.Spec.EndTime.Time
+	} else if playtest.Spec.Duration != nil {
+		end = playtest.Spec.StartTime.Add(playtest.Spec.Duration.Duration)
+	}
+
+	if minutes, err := strconv.Atoi(playtest.GetAnnotations()[ExtendByAnnotation]); err == nil {
+		end = end.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	return end
+}
+
+// isFatalServerReason returns true if a group server failure reason won't resolve without intervention
+func isFatalServerReason(reason string) bool {
+	return imagePullFailureReasons[reason] || reason == gamev1alpha1.RescheduleAttemptsExceededReason || reason == gamev1alpha1.InvalidTemplateReason
+}
+
+// setPlaytestConditions computes the ServersProvisioned and AllGroupsReady conditions from the group statuses
+func setPlaytestConditions(playtest *gamev1alpha1.Playtest, now time.Time, leadTime time.Duration) {
+	if playtest.Spec.DisableGameServers {
+		conditions.Delete(playtest, gamev1alpha1.ServersProvisionedCondition)
+		conditions.Delete(playtest, gamev1alpha1.AllGroupsReadyCondition)
+		return
+	}
+
+	total := len(playtest.Spec.Groups)
+	provisioned, ready := 0, 0
+	failures := []string{}
+	for _, group := range playtest.Spec.Groups {
+		groupStatus := getGroupStatus(playtest, group.Name)
+		if groupStatus == nil {
+			continue
+		}
+
+		if groupStatus.ServerRef != nil {
+			provisioned++
+		}
+
+		if groupStatus.Ready {
+			ready++
+		}
+
+		if isFatalServerReason(groupStatus.Reason) {
+			failures = append(failures, fmt.Sprintf("%s: %s", group.Name, groupStatus.Reason))
+		}
+	}
+
+	switch {
+	case provisioned == total:
+		conditions.MarkTrue(playtest, gamev1alpha1.ServersProvisionedCondition)
+	case now.Add(leadTime).Before(playtest.Spec.StartTime.Time):
+		conditions.MarkFalse(playtest, gamev1alpha1.ServersProvisionedCondition, gamev1alpha1.WaitingForProvisioningReason, clusterv1.ConditionSeverityInfo,
+			"servers will be created at %s", playtest.Spec.StartTime.Add(-leadTime).UTC().Format(time.RFC3339))
+	default:
+		conditions.MarkFalse(playtest, gamev1alpha1.ServersProvisionedCondition, gamev1alpha1.ProvisioningReason, clusterv1.ConditionSeverityInfo,
+			"%d of %d group servers created", provisioned, total)
+	}
+
+	switch {
+	case len(failures) > 0:
+		conditions.MarkFalse(playtest, gamev1alpha1.AllGroupsReadyCondition, gamev1alpha1.GroupServerFailedReason, clusterv1.ConditionSeverityError,
+			"%s", strings.Join(failures, "; "))
+	case ready == total:
+		conditions.MarkTrue(playtest, gamev1alpha1.AllGroupsReadyCondition)
+	default:
+		conditions.MarkFalse(playtest, gamev1alpha1.AllGroupsReadyCondition, gamev1alpha1.WaitingForServersReason, clusterv1.ConditionSeverityInfo,
+			"%d of %d groups ready", ready, total)
+	}
+}
+
+// setPlaytestPhase updates the playtest's conditions, then derives its phase from them and the clock.
+// leadTime is how long before the start time group servers are created.
+func setPlaytestPhase(playtest *gamev1alpha1.Playtest, leadTime time.Duration) {
+	now := time.Now()
+
+	setPlaytestConditions(playtest, now, leadTime)
+
+	var phase gamev1alpha1.PlaytestPhase
+	switch {
+	case playtest.Spec.Suspend:
+		phase = gamev1alpha1.PlaytestPhaseSuspended
+	case !now.Before(playtestEndTime(playtest)):
+		phase = gamev1alpha1.PlaytestPhaseEnded
+	case conditions.GetReason(playtest, gamev1alpha1.AllGroupsReadyCondition) == gamev1alpha1.GroupServerFailedReason:
+		phase = gamev1alpha1.PlaytestPhaseFailed
+	case !now.Before(playtest.Spec.StartTime.Time):
+		phase = gamev1alpha1.PlaytestPhaseInProgress
+	case conditions.IsTrue(playtest, gamev1alpha1.AllGroupsReadyCondition):
+		phase = gamev1alpha1.PlaytestPhaseReady
+	case now.Add(leadTime).After(playtest.Spec.StartTime.Time):
+		phase = gamev1alpha1.PlaytestPhaseProvisioning
+	default:
+		phase = gamev1alpha1.PlaytestPhaseScheduled
+	}
+
+	if phase == playtest.Status.Phase {
+		return
+	}
+
+	playtest.Status.Phase = phase
+
+	// only keep the latest transition into each phase
+	transitions := []gamev1alpha1.PlaytestPhaseTransition{}
+	for _, transition := range playtest.Status.PhaseTransitions {
+		if transition.Phase != phase {
+			transitions = append(transitions, transition)
+		}
+	}
+
+	playtest.Status.PhaseTransitions = append(transitions, gamev1alpha1.PlaytestPhaseTransition{
+		Phase: phase,
+		Time:  metav1.NewTime(now),
+	})
+}
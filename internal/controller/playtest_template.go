@@ -0,0 +1,164 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// resolvePlaytestTemplate fills in the playtest's unset settings from its PlaytestTemplate. The values
+// only live in memory and are never written back to the spec. It returns the resolved configuration to
+// record in status, or nil if the playtest doesn't use a template. Once the playtest has started, the
+// configuration recorded in status is used instead of the live template.
+// If the template doesn't exist, the configuration recorded in status is used if there is one, and a
+// NotFound error is returned along with it.
+func (r *PlaytestReconciler) resolvePlaytestTemplate(ctx context.Context, playtest *gamev1alpha1.Playtest) (*gamev1alpha1.PlaytestTemplateStatus, error) {
+	name := playtest.Spec.Template
+	if name == "" {
+		return nil, nil
+	}
+
+	started := !time.Now().Before(playtest.Spec.StartTime.Time)
+	if resolved := playtest.Status.Template; resolved != nil && resolved.Name == name && started {
+		applyPlaytestTemplate(playtest, &resolved.Spec)
+		return resolved.DeepCopy(), nil
+	}
+
+	template := &gamev1alpha1.PlaytestTemplate{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: playtest.GetNamespace(), Name: name}, template); err != nil {
+		err = fmt.Errorf("failed to get playtest template %q: %w", name, err)
+		if resolved := playtest.Status.Template; resolved != nil && resolved.Name == name && apierrors.IsNotFound(err) {
+			applyPlaytestTemplate(playtest, &resolved.Spec)
+			return resolved.DeepCopy(), err
+		}
+
+		return nil, err
+	}
+
+	applyPlaytestTemplate(playtest, &template.Spec)
+
+	return &gamev1alpha1.PlaytestTemplateStatus{
+		Name:       name,
+		Generation: template.GetGeneration(),
+		Spec:       playtestTemplateSpec(playtest),
+	}, nil
+}
+
+// markTemplateResolved sets the playtest's TemplateResolved condition from the outcome of resolvePlaytestTemplate
+func markTemplateResolved(playtest *gamev1alpha1.Playtest, template *gamev1alpha1.PlaytestTemplateStatus, err error) {
+	switch {
+	case playtest.Spec.Template == "":
+		conditions.Delete(playtest, gamev1alpha1.TemplateResolvedCondition)
+	case err == nil:
+		conditions.MarkTrue(playtest, gamev1alpha1.TemplateResolvedCondition)
+	case template != nil:
+		conditions.MarkFalse(playtest, gamev1alpha1.TemplateResolvedCondition, gamev1alpha1.TemplateNotFoundReason, clusterv1.ConditionSeverityWarning,
+			"%s, using the settings last resolved from generation %d", err.Error(), template.Generation)
+	default:
+		conditions.MarkFalse(playtest, gamev1alpha1.TemplateResolvedCondition, gamev1alpha1.TemplateNotFoundReason, clusterv1.ConditionSeverityError,
+			"%s", err.Error())
+	}
+}
+
+// applyPlaytestTemplate copies template settings into any the playtest hasn't set
+func applyPlaytestTemplate(playtest *gamev1alpha1.Playtest, template *gamev1alpha1.PlaytestTemplateSpec) {
+	spec := &playtest.Spec
+
+	if spec.Map == "" {
+		spec.Map = template.Map
+	}
+
+	if spec.MinGroups == 0 {
+		spec.MinGroups = template.MinGroups
+	}
+
+	if spec.PlayersPerGroup == 0 {
+		spec.PlayersPerGroup = template.PlayersPerGroup
+	}
+
+	if spec.FeedbackURL == "" {
+		spec.FeedbackURL = template.FeedbackURL
+	}
+
+	// an empty list is an override, only nil is unset
+	if spec.GameServerCmdArgs == nil {
+		spec.GameServerCmdArgs = append([]string(nil), template.GameServerCmdArgs...)
+	}
+
+	if spec.IncludeReadinessProbe == nil {
+		spec.IncludeReadinessProbe = pointer.Bool(template.IncludeReadinessProbe)
+	}
+
+	if spec.GameServerEnv == nil {
+		for _, env := range template.GameServerEnv {
+			spec.GameServerEnv = append(spec.GameServerEnv, *env.DeepCopy())
+		}
+	}
+
+	if spec.GameServerPorts == nil {
+		spec.GameServerPorts = append(spec.GameServerPorts, template.GameServerPorts...)
+	}
+
+	if spec.PendingTimeout == nil && template.PendingTimeout != nil {
+		spec.PendingTimeout = template.PendingTimeout.DeepCopy()
+	}
+}
+
+// playtestTemplateSpec returns the playtest's settings that can come from a template
+func playtestTemplateSpec(playtest *gamev1alpha1.Playtest) gamev1alpha1.PlaytestTemplateSpec {
+	spec := playtest.DeepCopy().Spec
+
+	return gamev1alpha1.PlaytestTemplateSpec{
+		Map:                   spec.Map,
+		MinGroups:             spec.MinGroups,
+		PlayersPerGroup:       spec.PlayersPerGroup,
+		FeedbackURL:           spec.FeedbackURL,
+		GameServerCmdArgs:     spec.GameServerCmdArgs,
+		IncludeReadinessProbe: pointer.BoolDeref(spec.IncludeReadinessProbe, false),
+		GameServerEnv:         spec.GameServerEnv,
+		GameServerPorts:       spec.GameServerPorts,
+		PendingTimeout:        spec.PendingTimeout,
+	}
+}
+
+// playtestsForTemplate maps a PlaytestTemplate to the Playtests that reference it
+func (r *PlaytestReconciler) playtestsForTemplate(obj client.Object) []reconcile.Request {
+	playtests := &gamev1alpha1.PlaytestList{}
+	if err := r.List(context.Background(), playtests, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, playtest := range playtests.Items {
+		if playtest.Spec.Template == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&playtest)})
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+const (
+	// PlaytestScheduleLabel is set on Playtests created by a PlaytestSchedule to the schedule's name
+	PlaytestScheduleLabel = "believer.dev/playtest-schedule"
+
+	// PlaytestScheduleFinalizer hands a PlaytestSchedule's Playtests back to retention before it's deleted
+	PlaytestScheduleFinalizer = "believer.dev/playtest-schedule"
+
+	// defaultCreateBefore is how long before each occurrence its Playtest is created
+	defaultCreateBefore = 1 * time.Hour
+
+	// defaultHistoryLimit is how many ended Playtests a schedule keeps
+	defaultHistoryLimit = 3
+
+	// maxSkippedOccurrences bounds the search for the next occurrence that isn't skipped
+	maxSkippedOccurrences = 100
+)
+
+// PlaytestScheduleReconciler reconciles a PlaytestSchedule object
+type PlaytestScheduleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=game.believer.dev,resources=playtestschedules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=game.believer.dev,resources=playtestschedules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=game.believer.dev,resources=playtestschedules/finalizers,verbs=update
+//+kubebuilder:rbac:groups=game.believer.dev,resources=playtests,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile creates a Playtest ahead of each occurrence of the schedule and cleans up old ones
+func (r *PlaytestScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	schedule := &gamev1alpha1.PlaytestSchedule{}
+	if err := r.Get(ctx, req.NamespacedName, schedule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	patchHelper, err := patch.NewHelper(schedule, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// No matter what happens during reconciliation, we want to try to patch the object at the end and catch updates
+	defer func() {
+		if err := patchHelper.Patch(ctx, schedule); err != nil {
+			log.Error(err, "error patching object")
+		}
+	}()
+
+	// the schedule's Playtests outlive it, so let go of them before it's gone
+	if !schedule.GetDeletionTimestamp().IsZero() {
+		if err := r.releasePlaytests(ctx, schedule); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		controllerutil.RemoveFinalizer(schedule, PlaytestScheduleFinalizer)
+
+		return ctrl.Result{}, nil
+	}
+
+	controllerutil.AddFinalizer(schedule, PlaytestScheduleFinalizer)
+
+	return r.reconcileSchedule(ctx, schedule)
+}
+
+// releasePlaytests removes the schedule's label and any owner reference to it from the Playtests it
+// created, so they're left running and then pruned by retention like any other Playtest
+func (r *PlaytestScheduleReconciler) releasePlaytests(ctx context.Context, schedule *gamev1alpha1.PlaytestSchedule) error {
+	log := log.FromContext(ctx)
+
+	playtests := &gamev1alpha1.PlaytestList{}
+	if err := r.List(ctx, playtests, client.InNamespace(schedule.GetNamespace()), client.MatchingLabels{
+		PlaytestScheduleLabel: schedule.GetName(),
+	}); err != nil {
+		return err
+	}
+
+	for i := range playtests.Items {
+		playtest := &playtests.Items[i]
+
+		log.Info("releasing scheduled playtest", "playtest", playtest.GetName())
+
+		before := playtest.DeepCopy()
+		delete(playtest.Labels, PlaytestScheduleLabel)
+
+		// Playtests created before schedules stopped owning them would otherwise be garbage collected
+		ownerReferences := []metav1.OwnerReference{}
+		for _, ownerReference := range playtest.GetOwnerReferences() {
+			if ownerReference.UID != schedule.GetUID() {
+				ownerReferences = append(ownerReferences, ownerReference)
+			}
+		}
+		playtest.SetOwnerReferences(ownerReferences)
+
+		if err := r.Patch(ctx, playtest, client.MergeFrom(before)); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *PlaytestScheduleReconciler) reconcileSchedule(ctx context.Context, schedule *gamev1alpha1.PlaytestSchedule) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	sched, err := cron.ParseStandard(schedule.Spec.Schedule)
+	if err != nil {
+		conditions.MarkFalse(schedule, gamev1alpha1.ScheduleValidCondition, gamev1alpha1.InvalidScheduleReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		schedule.Status.NextScheduleTime = nil
+
+		// nothing to do until the spec changes
+		return ctrl.Result{}, nil
+	}
+
+	loc, err := time.LoadLocation(schedule.Spec.TimeZone)
+	if err != nil {
+		conditions.MarkFalse(schedule, gamev1alpha1.ScheduleValidCondition, gamev1alpha1.InvalidTimeZoneReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		schedule.Status.NextScheduleTime = nil
+
+		return ctrl.Result{}, nil
+	}
+
+	conditions.MarkTrue(schedule, gamev1alpha1.ScheduleValidCondition)
+
+	if err := r.pruneHistory(ctx, schedule); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if schedule.Spec.Suspend {
+		schedule.Status.NextScheduleTime = nil
+
+		return ctrl.Result{}, nil
+	}
+
+	next := nextOccurrence(sched, loc, schedule.Spec.SkipOccurrences, time.Now())
+	if next.IsZero() {
+		log.Info("schedule has no upcoming occurrences")
+		schedule.Status.NextScheduleTime = nil
+
+		return ctrl.Result{}, nil
+	}
+
+	schedule.Status.NextScheduleTime = &metav1.Time{Time: next}
+
+	createBefore := defaultCreateBefore
+	if schedule.Spec.CreateBefore != nil {
+		createBefore = schedule.Spec.CreateBefore.Duration
+	}
+
+	if wait := time.Until(next.Add(-createBefore)); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	// only create each occurrence's playtest once, so deleting it by hand sticks
+	if last := schedule.Status.LastScheduleTime; last == nil || !last.Time.Equal(next) {
+		playtest := newScheduledPlaytest(schedule, next)
+
+		log.Info("creating scheduled playtest", "playtest", playtest.GetName(), "startTime", next)
+		if err := r.Create(ctx, playtest); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctrl.Result{}, err
+		}
+
+		schedule.Status.LastScheduleTime = &metav1.Time{Time: next}
+		schedule.Status.Playtests = append(schedule.Status.Playtests, corev1.LocalObjectReference{Name: playtest.GetName()})
+	}
+
+	// once this occurrence starts, the one after it is next
+	return ctrl.Result{RequeueAfter: time.Until(next) + time.Second}, nil
+}
+
+// pruneHistory archives and deletes the oldest ended Playtests created by the schedule beyond its history
+// limit, and records the ones that remain
+func (r *PlaytestScheduleReconciler) pruneHistory(ctx context.Context, schedule *gamev1alpha1.PlaytestSchedule) error {
+	log := log.FromContext(ctx)
+
+	playtests := &gamev1alpha1.PlaytestList{}
+	if err := r.List(ctx, playtests, client.InNamespace(schedule.GetNamespace()), client.MatchingLabels{
+		PlaytestScheduleLabel: schedule.GetName(),
+	}); err != nil {
+		return err
+	}
+
+	sort.Slice(playtests.Items, func(i, j int) bool {
+		return playtests.Items[i].Spec.StartTime.Before(&playtests.Items[j].Spec.StartTime)
+	})
+
+	historyLimit := int32(defaultHistoryLimit)
+	if schedule.Spec.HistoryLimit != nil {
+		historyLimit = *schedule.Spec.HistoryLimit
+	}
+
+	ended := 0
+	for i := range playtests.Items {
+		if historyExpired(&playtests.Items[i]) {
+			ended++
+		}
+	}
+
+	schedule.Status.Playtests = []corev1.LocalObjectReference{}
+	for i := range playtests.Items {
+		playtest := &playtests.Items[i]
+
+		if ended > int(historyLimit) && historyExpired(playtest) {
+			log.Info("deleting old scheduled playtest", "playtest", playtest.GetName())
+
+			if err := archivePlaytest(ctx, r.Client, playtest); err != nil {
+				return err
+			}
+
+			if err := r.Delete(ctx, playtest); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+
+			ended--
+			continue
+		}
+
+		schedule.Status.Playtests = append(schedule.Status.Playtests, corev1.LocalObjectReference{Name: playtest.GetName()})
+	}
+
+	return nil
+}
+
+// historyExpired returns true if the playtest has ended and counts toward the schedule's history limit
+func historyExpired(playtest *gamev1alpha1.Playtest) bool {
+	return !keptWhenEnded(playtest) && !time.Now().Before(playtestEndTime(playtest))
+}
+
+// nextOccurrence returns the first time after now the schedule fires in loc that isn't skipped, or
+// the zero time if there is none
+func nextOccurrence(sched cron.Schedule, loc *time.Location, skip []metav1.Time, now time.Time) time.Time {
+	next := now.In(loc)
+	for i := 0; i < maxSkippedOccurrences; i++ {
+		next = sched.Next(next)
+		if next.IsZero() {
+			return next
+		}
+
+		skipped := false
+		for _, s := range skip {
+			skipped = skipped || s.Time.Equal(next)
+		}
+
+		if !skipped {
+			return next
+		}
+	}
+
+	return time.Time{}
+}
+
+// newScheduledPlaytest returns the Playtest for an occurrence of the schedule starting at startTime. It
+// isn't owned by the schedule, so deleting the schedule doesn't take its Playtests with it.
+func newScheduledPlaytest(schedule *gamev1alpha1.PlaytestSchedule, startTime time.Time) *gamev1alpha1.Playtest {
+	template := schedule.Spec.Template.DeepCopy()
+
+	labels := map[string]string{}
+	for k, v := range template.Metadata.Labels {
+		labels[k] = v
+	}
+	labels[PlaytestScheduleLabel] = schedule.GetName()
+
+	playtest := &gamev1alpha1.Playtest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", schedule.GetName(), startTime.UTC().Format("200601021504")),
+			Namespace:   schedule.GetNamespace(),
+			Labels:      labels,
+			Annotations: template.Metadata.Annotations,
+		},
+		Spec: template.Spec,
+	}
+
+	playtest.Spec.StartTime = metav1.NewTime(startTime)
+
+	return playtest
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PlaytestScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gamev1alpha1.PlaytestSchedule{}).
+		Watches(
+			&source.Kind{Type: &gamev1alpha1.Playtest{}},
+			handler.EnqueueRequestsFromMapFunc(scheduleForPlaytest),
+		).
+		Complete(r)
+}
+
+// scheduleForPlaytest maps a Playtest to the PlaytestSchedule that created it
+func scheduleForPlaytest(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[PlaytestScheduleLabel]
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}}}
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+var _ = Describe("PlaytestScheduleController", func() {
+	Describe("Next Occurrence", func() {
+		var sched cron.Schedule
+		var loc *time.Location
+		var now time.Time
+
+		BeforeEach(func() {
+			var err error
+			sched, err = cron.ParseStandard("0 17 * * 2,4")
+			Expect(err).ToNot(HaveOccurred())
+
+			loc, err = time.LoadLocation("America/Los_Angeles")
+			Expect(err).ToNot(HaveOccurred())
+
+			// Monday
+			now = time.Date(2024, time.January, 1, 12, 0, 0, 0, loc)
+		})
+
+		It("should fire in the schedule's time zone", func() {
+			next := nextOccurrence(sched, loc, nil, now)
+			Expect(next.Equal(time.Date(2024, time.January, 2, 17, 0, 0, 0, loc))).To(BeTrue())
+		})
+
+		It("should skip occurrences", func() {
+			skip := []metav1.Time{metav1.NewTime(time.Date(2024, time.January, 2, 17, 0, 0, 0, loc))}
+			next := nextOccurrence(sched, loc, skip, now)
+			Expect(next.Equal(time.Date(2024, time.January, 4, 17, 0, 0, 0, loc))).To(BeTrue())
+		})
+	})
+
+	Describe("Scheduled Playtests", func() {
+		It("should stamp out the template at the occurrence", func() {
+			schedule := &gamev1alpha1.PlaytestSchedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "weekly",
+					Namespace: "game-servers",
+				},
+				Spec: gamev1alpha1.PlaytestScheduleSpec{
+					Template: gamev1alpha1.PlaytestScheduleTemplate{
+						Metadata: gamev1alpha1.PlaytestTemplateMetadata{
+							Labels: map[string]string{"team": "core"},
+						},
+						Spec: gamev1alpha1.PlaytestSpec{
+							Version:   "7c3997c5",
+							MinGroups: 5,
+						},
+					},
+				},
+			}
+
+			startTime := time.Date(2024, time.January, 2, 17, 0, 0, 0, time.UTC)
+			playtest := newScheduledPlaytest(schedule, startTime)
+
+			Expect(playtest.GetName()).To(Equal("weekly-202401021700"))
+			Expect(playtest.GetNamespace()).To(Equal("game-servers"))
+			Expect(playtest.GetLabels()).To(HaveKeyWithValue("team", "core"))
+			Expect(playtest.GetLabels()).To(HaveKeyWithValue(PlaytestScheduleLabel, "weekly"))
+			Expect(playtest.GetOwnerReferences()).To(BeEmpty())
+			Expect(playtest.Spec.StartTime.Time.Equal(startTime)).To(BeTrue())
+			Expect(playtest.Spec.MinGroups).To(Equal(5))
+			Expect(schedule.Spec.Template.Metadata.Labels).To(HaveLen(1))
+		})
+	})
+
+	Describe("Schedule History", func() {
+		var schedule *gamev1alpha1.PlaytestSchedule
+		var sr *PlaytestScheduleReconciler
+
+		createEndedPlaytest := func(name string, startTime time.Time, annotations map[string]string) {
+			playtest := &gamev1alpha1.Playtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "default",
+					Name:        name,
+					Labels:      map[string]string{PlaytestScheduleLabel: schedule.GetName()},
+					Annotations: annotations,
+				},
+				Spec: gamev1alpha1.PlaytestSpec{
+					Version:         "7c3997c5",
+					StartTime:       metav1.NewTime(startTime),
+					Duration:        &metav1.Duration{Duration: time.Hour},
+					MinGroups:       1,
+					PlayersPerGroup: 2,
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), playtest)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			sr = &PlaytestScheduleReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			schedule = &gamev1alpha1.PlaytestSchedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "history",
+				},
+				Spec: gamev1alpha1.PlaytestScheduleSpec{
+					HistoryLimit: pointer.Int32(1),
+				},
+			}
+
+			createEndedPlaytest("history-new", time.Now().Add(-3*time.Hour), nil)
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(context.Background(), &gamev1alpha1.Playtest{}, client.InNamespace("default"),
+				client.MatchingLabels{PlaytestScheduleLabel: schedule.GetName()})).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(context.Background(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "history-old-archive"},
+			}))).To(Succeed())
+		})
+
+		It("should archive and delete playtests beyond the history limit", func() {
+			createEndedPlaytest("history-old", time.Now().Add(-5*time.Hour), nil)
+
+			Expect(sr.pruneHistory(context.Background(), schedule)).To(Succeed())
+
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "history-old"}, &gamev1alpha1.Playtest{})).ToNot(Succeed())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "history-old-archive"}, &corev1.ConfigMap{})).To(Succeed())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "history-new"}, &gamev1alpha1.Playtest{})).To(Succeed())
+			Expect(schedule.Status.Playtests).To(Equal([]corev1.LocalObjectReference{{Name: "history-new"}}))
+		})
+
+		It("should keep playtests that aren't to be pruned", func() {
+			createEndedPlaytest("history-old", time.Now().Add(-5*time.Hour), map[string]string{DoNotPruneAnnotation: "true"})
+
+			Expect(sr.pruneHistory(context.Background(), schedule)).To(Succeed())
+
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "history-old"}, &gamev1alpha1.Playtest{})).To(Succeed())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "history-old-archive"}, &corev1.ConfigMap{})).ToNot(Succeed())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "history-new"}, &gamev1alpha1.Playtest{})).To(Succeed())
+			Expect(schedule.Status.Playtests).To(HaveLen(2))
+		})
+	})
+
+	Describe("Schedule Deletion", func() {
+		var schedule *gamev1alpha1.PlaytestSchedule
+		var playtest *gamev1alpha1.Playtest
+		var sr *PlaytestScheduleReconciler
+
+		reconcileSchedule := func() {
+			_, err := sr.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(schedule)})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		BeforeEach(func() {
+			sr = &PlaytestScheduleReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			schedule = &gamev1alpha1.PlaytestSchedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "deleted",
+				},
+				Spec: gamev1alpha1.PlaytestScheduleSpec{
+					Schedule: "0 17 * * 2",
+					Suspend:  true,
+					Template: gamev1alpha1.PlaytestScheduleTemplate{
+						Spec: gamev1alpha1.PlaytestSpec{
+							Version:         "7c3997c5",
+							StartTime:       metav1.Now(),
+							MinGroups:       1,
+							PlayersPerGroup: 2,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), schedule)).To(Succeed())
+
+			playtest = newScheduledPlaytest(schedule, time.Now().Add(-time.Minute))
+			// as created before schedules stopped owning their playtests
+			playtest.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: gamev1alpha1.GroupVersion.String(),
+				Kind:       "PlaytestSchedule",
+				Name:       schedule.GetName(),
+				UID:        schedule.GetUID(),
+				Controller: pointer.Bool(true),
+			}})
+			Expect(k8sClient.Create(context.Background(), playtest)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(context.Background(), playtest))).To(Succeed())
+		})
+
+		It("should leave its playtests to retention", func() {
+			reconcileSchedule()
+			Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(schedule), schedule)).To(Succeed())
+			Expect(schedule.GetFinalizers()).To(ContainElement(PlaytestScheduleFinalizer))
+
+			Expect(k8sClient.Delete(context.Background(), schedule)).To(Succeed())
+			reconcileSchedule()
+
+			Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(schedule), &gamev1alpha1.PlaytestSchedule{})).ToNot(Succeed())
+
+			released := &gamev1alpha1.Playtest{}
+			Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(playtest), released)).To(Succeed())
+			Expect(released.GetLabels()).ToNot(HaveKey(PlaytestScheduleLabel))
+			Expect(released.GetOwnerReferences()).To(BeEmpty())
+			Expect(prunable(released)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,137 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// Names of the ports every game server gets
+const (
+	GamePortName     = "game"
+	NetImguiPortName = "netimgui"
+	StatusPortName   = "status"
+)
+
+// portArgFormat matches an Arg with exactly one %d and no other formatting verbs, allowing %% for a literal %
+var portArgFormat = regexp.MustCompile(`^([^%]|%%)*%d([^%]|%%)*$`)
+
+// allocatedPort is a GameServerPort with a port number picked from its range
+type allocatedPort struct {
+	gamev1alpha1.GameServerPort
+
+	Port int32
+}
+
+// builtinPorts returns the ports every game server listens on, configured from the operator's flags
+func (r *GameServerReconciler) builtinPorts() []gamev1alpha1.GameServerPort {
+	return []gamev1alpha1.GameServerPort{
+		{
+			Name:     GamePortName,
+			Protocol: corev1.ProtocolUDP,
+			MinPort:  r.GamePortMin,
+			MaxPort:  r.GamePortMax,
+			Policy:   gamev1alpha1.PortPolicyOffset,
+			Arg:      "-port=%d",
+		},
+		{
+			Name:     NetImguiPortName,
+			Protocol: corev1.ProtocolTCP,
+			MinPort:  r.NetImguiPortMin,
+			Policy:   gamev1alpha1.PortPolicyOffset,
+			Arg:      "-NetImguiClientPort=%d",
+		},
+		{
+			Name:     StatusPortName,
+			Protocol: corev1.ProtocolTCP,
+			MinPort:  r.StatusPortMin,
+			Policy:   gamev1alpha1.PortPolicyOffset,
+			Arg:      "-RemoteStatusPort=%d",
+		},
+	}
+}
+
+// allocatePorts picks a port number for each built-in and additional port of the GameServer.
+//
+// We do not need to keep track of which ports we've assigned. This controller watches
+// Pods that are children of GameServers, and can detect when one is unschedulable due to a port
+// conflict, then delete the Pod. Yes, as we start to run out of overall ports there will be some
+// thrashing, but doing it this way allows us to scale out nodes without needing to maintain a
+// mapping of nodes and ports.
+func (r *GameServerReconciler) allocatePorts(ctx context.Context, gameServer *gamev1alpha1.GameServer) []allocatedPort {
+	log := log.FromContext(ctx)
+
+	// all Offset ports share the offset of the game port, so a server's ports line up across ranges
+	offset := rand.Int31n(r.GamePortMax - r.GamePortMin)
+
+	ports := []allocatedPort{}
+	seen := map[string]bool{}
+	for _, port := range append(r.builtinPorts(), gameServer.Spec.Ports...) {
+		if seen[port.Name] {
+			log.Info("ignoring duplicate port", "port", port.Name)
+			continue
+		}
+		seen[port.Name] = true
+
+		if port.Protocol == "" {
+			port.Protocol = corev1.ProtocolTCP
+		}
+
+		ports = append(ports, allocatedPort{
+			GameServerPort: port,
+			Port:           r.pickPort(port, offset),
+		})
+	}
+
+	return ports
+}
+
+// pickPort returns a port number from the port's range according to its policy
+func (r *GameServerReconciler) pickPort(port gamev1alpha1.GameServerPort, offset int32) int32 {
+	size := port.MaxPort - port.MinPort
+	if port.MaxPort == 0 {
+		size = r.GamePortMax - r.GamePortMin
+	}
+
+	if size <= 0 {
+		return port.MinPort
+	}
+
+	if port.Policy == gamev1alpha1.PortPolicyRandom {
+		return rand.Int31n(size) + port.MinPort
+	}
+
+	return port.MinPort + offset%size
+}
+
+// portArg returns the commandline argument passing the port to the game server. Arg is a user supplied
+// format, so anything but a single %d is refused rather than rendered as garbage.
+func portArg(port allocatedPort) (string, error) {
+	if !portArgFormat.MatchString(port.Arg) {
+		return "", fmt.Errorf("arg %q for port %s must contain exactly one %%d", port.Arg, port.Name)
+	}
+
+	return fmt.Sprintf(port.Arg, port.Port), nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// provisioningLeadTime returns how long before the playtest's start time its group servers are created
+func (r *PlaytestReconciler) provisioningLeadTime(playtest *gamev1alpha1.Playtest) time.Duration {
+	if playtest.Spec.ProvisioningLeadTime != nil {
+		return playtest.Spec.ProvisioningLeadTime.Duration
+	}
+
+	if r.DefaultProvisioningLeadTime > 0 {
+		return r.DefaultProvisioningLeadTime
+	}
+
+	return defaultProvisioningLeadTime
+}
+
+// provisioningBatch limits how many group servers are created in a single reconcile, so a large
+// playtest doesn't pull its image onto every node at once
+type provisioningBatch struct {
+	// remaining is how many more servers may be created, or -1 for no limit
+	remaining int
+	// created is how many servers have been created so far
+	created int
+	// deferred is set once a server couldn't be created because the batch was used up
+	deferred bool
+	// wait is how long until the next batch may start
+	wait time.Duration
+}
+
+// newProvisioningBatch returns the batch of group servers the playtest may create now
+func (r *PlaytestReconciler) newProvisioningBatch(playtest *gamev1alpha1.Playtest) *provisioningBatch {
+	if r.ProvisioningBatchSize <= 0 {
+		return &provisioningBatch{remaining: -1}
+	}
+
+	batch := &provisioningBatch{
+		remaining: r.ProvisioningBatchSize,
+		wait:      r.ProvisioningBatchInterval,
+	}
+
+	if last := playtest.Status.LastProvisioningBatchTime; last != nil {
+		if wait := time.Until(last.Add(r.ProvisioningBatchInterval)); wait > 0 {
+			batch.remaining = 0
+			batch.wait = wait
+		}
+	}
+
+	return batch
+}
+
+// take claims a server from the batch, returning false if the batch is used up
+func (b *provisioningBatch) take() bool {
+	if b.remaining == 0 {
+		b.deferred = true
+		return false
+	}
+
+	if b.remaining > 0 {
+		b.remaining--
+	}
+	b.created++
+
+	return true
+}
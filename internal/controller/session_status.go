@@ -0,0 +1,114 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// sessionStatusTimeout bounds how long a game server's status endpoint may take to answer
+const sessionStatusTimeout = 2 * time.Second
+
+// sessionStatus is the part of a game server's /status response the operator reads. Servers that don't
+// report these fields are left without live player counts.
+type sessionStatus struct {
+	Players          *int32 `json:"players"`
+	AcceptingJoiners *bool  `json:"acceptingJoiners"`
+}
+
+// fetchSessionStatus reads the session status from a game server's status endpoint
+func fetchSessionStatus(ctx context.Context, httpClient *http.Client, address string) (*sessionStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, sessionStatusTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/status", address), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status endpoint returned %s", resp.Status)
+	}
+
+	status := &sessionStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// pollSessionStatus updates the game server's session status if it's due, and returns how long until
+// the next poll. Every status update triggers another reconcile, so a server polled within the last
+// interval isn't polled again, even if it didn't answer.
+func (r *GameServerReconciler) pollSessionStatus(ctx context.Context, gameServer *gamev1alpha1.GameServer) time.Duration {
+	if last := gameServer.Status.LastSessionStatusPollTime; last != nil {
+		if wait := time.Until(last.Add(r.SessionStatusInterval)); wait > 0 {
+			return wait
+		}
+	}
+
+	r.updateSessionStatus(ctx, gameServer)
+
+	return r.SessionStatusInterval
+}
+
+// updateSessionStatus records the game server's live player count and whether it takes joiners. Failures
+// are logged and leave the last known values in place, since the server may just be busy.
+func (r *GameServerReconciler) updateSessionStatus(ctx context.Context, gameServer *gamev1alpha1.GameServer) {
+	log := log.FromContext(ctx)
+
+	if gameServer.Status.InternalIP == "" || gameServer.Status.StatusPort == 0 {
+		return
+	}
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	now := metav1.Now()
+	gameServer.Status.LastSessionStatusPollTime = &now
+
+	address := net.JoinHostPort(gameServer.Status.InternalIP, strconv.Itoa(int(gameServer.Status.StatusPort)))
+	status, err := fetchSessionStatus(ctx, httpClient, address)
+	if err != nil {
+		log.Info("unable to read session status", "address", address, "reason", err.Error())
+		return
+	}
+
+	gameServer.Status.Players = status.Players
+	gameServer.Status.AcceptingJoiners = status.AcceptingJoiners
+	gameServer.Status.LastSessionStatusTime = &now
+}
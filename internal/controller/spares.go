@@ -0,0 +1,241 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// SpareLabel marks a playtest's GameServers that are on standby rather than serving a group
+const SpareLabel = "believer.dev/spare"
+
+// GroupServerFailoverReason is the Event reason recorded when a group server is swapped for a spare
+const GroupServerFailoverReason = "GroupServerFailover"
+
+// crashLoopBackOffReason is the container waiting reason for a server that keeps crashing
+const crashLoopBackOffReason = "CrashLoopBackOff"
+
+// groupServerFailed returns true if the server's Pod has died or keeps crashing
+func groupServerFailed(gameServer *gamev1alpha1.GameServer) bool {
+	// a server that gave up on port conflicts won't get a Pod again without a restart
+	if conditions.GetReason(gameServer, gamev1alpha1.PodScheduledCondition) == gamev1alpha1.RescheduleAttemptsExceededReason {
+		return true
+	}
+
+	podStatus := gameServer.Status.PodStatus
+	if podStatus == nil {
+		return false
+	}
+
+	if podStatus.Phase == corev1.PodFailed {
+		return true
+	}
+
+	for _, containerStatus := range podStatus.ContainerStatuses {
+		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == crashLoopBackOffReason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listSpares returns the playtest's standby GameServers, leaving out any being deleted
+func (r *PlaytestReconciler) listSpares(ctx context.Context, playtest *gamev1alpha1.Playtest) ([]gamev1alpha1.GameServer, error) {
+	gameServers := &gamev1alpha1.GameServerList{}
+	if err := r.List(ctx, gameServers, client.InNamespace(playtest.GetNamespace()), client.MatchingLabels{
+		PlaytestLabel: playtest.GetName(),
+		SpareLabel:    "true",
+	}); err != nil {
+		return nil, err
+	}
+
+	spares := []gamev1alpha1.GameServer{}
+	for _, gameServer := range gameServers.Items {
+		if metav1.IsControlledBy(&gameServer, playtest) && gameServer.GetDeletionTimestamp().IsZero() {
+			spares = append(spares, gameServer)
+		}
+	}
+
+	return spares, nil
+}
+
+// spareMatches returns true if the spare runs the given version, map and args
+func spareMatches(spare *gamev1alpha1.GameServer, settings groupServerSettings) bool {
+	return spare.Spec.Version == settings.Version && spare.Spec.Map == settings.Map && equality.Semantic.DeepEqual(spare.Spec.CmdArgs, settings.CmdArgs)
+}
+
+// reconcileSpares keeps want spares on the playtest's current version, map and args, replacing any that
+// are out of date or have failed. New spares are taken from the batch after the group servers.
+func (r *PlaytestReconciler) reconcileSpares(ctx context.Context, playtest *gamev1alpha1.Playtest, want int, batch *provisioningBatch) error {
+	log := log.FromContext(ctx)
+
+	spares, err := r.listSpares(ctx, playtest)
+	if err != nil {
+		return err
+	}
+
+	settings := resolveGroupServer(playtest, gamev1alpha1.PlaytestGroup{})
+
+	kept := 0
+	for i := range spares {
+		spare := &spares[i]
+		if kept < want && spareMatches(spare, settings) && !groupServerFailed(spare) {
+			kept++
+			continue
+		}
+
+		log.Info("deleting spare gameserver", "gameserver", spare.GetName())
+
+		if err := r.Client.Delete(ctx, spare); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	for ; kept < want && batch.take(); kept++ {
+		spare := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: sanitizeServerName(playtest.GetName(), "spare", "spare") + "-",
+				Namespace:    playtest.GetNamespace(),
+				Labels: map[string]string{
+					PlaytestLabel:         playtest.GetName(),
+					SpareLabel:            "true",
+					"believer.dev/commit": settings.Commit,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: gamev1alpha1.GroupVersion.String(),
+						Kind:       "Playtest",
+						Name:       playtest.GetName(),
+						UID:        playtest.GetUID(),
+						Controller: pointer.Bool(true),
+					},
+				},
+			},
+			Spec: gamev1alpha1.GameServerSpec{
+				Version:               settings.Version,
+				Map:                   settings.Map,
+				IncludeReadinessProbe: settings.IncludeReadinessProbe,
+				CmdArgs:               settings.CmdArgs,
+				Env:                   playtest.Spec.GameServerEnv,
+				Ports:                 playtest.Spec.GameServerPorts,
+				PendingTimeout:        playtest.Spec.PendingTimeout,
+				// no group yet, but this gets the server an allowlist to fill in once it's claimed
+				Playtest: &gamev1alpha1.GameServerPlaytest{
+					Name: playtest.GetName(),
+				},
+			},
+		}
+
+		log.Info("creating spare gameserver")
+
+		if err := r.Client.Create(ctx, spare); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// claimSpare hands a ready spare matching the group's version, map and args over to the group. It returns
+// nil if there's no such spare. The group is recorded in the spare's spec, which is what ties it to the
+// group until the playtest's status catches up.
+func (r *PlaytestReconciler) claimSpare(ctx context.Context, playtest *gamev1alpha1.Playtest, settings groupServerSettings, groupServerPlaytest *gamev1alpha1.GameServerPlaytest) (*gamev1alpha1.GameServer, error) {
+	spares, err := r.listSpares(ctx, playtest)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range spares {
+		spare := &spares[i]
+		if !spare.Status.Ready || !spareMatches(spare, settings) {
+			continue
+		}
+
+		before := spare.DeepCopy()
+		delete(spare.Labels, SpareLabel)
+		spare.Spec.Playtest = groupServerPlaytest
+
+		// the spare's args were expanded without a group, so it needs a fresh Pod if they depend on one
+		if referencesGroup(settings.CmdArgs...) || referencesGroup(envValues(playtest.Spec.GameServerEnv)...) {
+			if spare.Annotations == nil {
+				spare.Annotations = map[string]string{}
+			}
+			spare.Annotations[RestartAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		// the lock keeps two groups from claiming the same spare off a stale cache
+		if err := r.Client.Patch(ctx, spare, client.MergeFromWithOptions(before, client.MergeFromWithOptimisticLock{})); err != nil {
+			if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		return spare, nil
+	}
+
+	return nil, nil
+}
+
+// claimedServer returns the server already claimed for the group other than the named one, or nil if
+// there is none. A claim can land before the playtest's status records it, and the next failover must
+// not claim a second spare.
+func (r *PlaytestReconciler) claimedServer(ctx context.Context, playtest *gamev1alpha1.Playtest, group, exclude string) (*gamev1alpha1.GameServer, error) {
+	gameServers := &gamev1alpha1.GameServerList{}
+	if err := r.List(ctx, gameServers, client.InNamespace(playtest.GetNamespace()), client.MatchingLabels{
+		PlaytestLabel: playtest.GetName(),
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := range gameServers.Items {
+		gameServer := &gameServers.Items[i]
+		if gameServer.GetName() == exclude || gameServer.GetLabels()[SpareLabel] != "" || !metav1.IsControlledBy(gameServer, playtest) || !gameServer.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		if gameServer.Spec.Playtest != nil && gameServer.Spec.Playtest.Group == group {
+			return gameServer, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// envValues returns the values of the env vars
+func envValues(env []corev1.EnvVar) []string {
+	values := []string{}
+	for _, envVar := range env {
+		values = append(values, envVar.Value)
+	}
+
+	return values
+}
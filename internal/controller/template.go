@@ -0,0 +1,115 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	gamev1alpha1 "github.com/believer-oss/f11r-operator/api/v1alpha1"
+)
+
+// templateFuncs are the functions available to CmdArgs and Env templates in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// gameServerTemplateData is the data CmdArgs and Env templates are expanded with, e.g.
+// "-SessionName={{ .Playtest }}-{{ .GroupIndex }}" or "-VoicePort={{ index .Ports \"voice\" }}"
+type gameServerTemplateData struct {
+	Name        string
+	DisplayName string
+	Version     string
+	Map         string
+
+	Playtest   string
+	Group      string
+	GroupIndex int
+	Users      []string
+
+	Ports map[string]int32
+
+	// The node's external IP isn't offered. It's only known once the Pod is scheduled, usually after the
+	// container has started, so servers read it from /var/run/fellowship/external-ip instead.
+}
+
+func newGameServerTemplateData(gameServer *gamev1alpha1.GameServer, ports []allocatedPort) *gameServerTemplateData {
+	data := &gameServerTemplateData{
+		Name:        gameServer.GetName(),
+		DisplayName: gameServer.Spec.DisplayName,
+		Version:     gameServer.Spec.Version,
+		Map:         gameServer.Spec.Map,
+		Users:       []string{},
+		Ports:       map[string]int32{},
+	}
+
+	if playtest := gameServer.Spec.Playtest; playtest != nil {
+		data.Playtest = playtest.Name
+		data.Group = playtest.Group
+		data.GroupIndex = playtest.GroupIndex
+		data.Users = append(data.Users, playtest.Users...)
+	}
+
+	for _, port := range ports {
+		data.Ports[port.Name] = port.Port
+	}
+
+	return data
+}
+
+// groupTemplateFields are the template fields that differ between the servers of a playtest's groups.
+// ".Group" also covers ".GroupIndex".
+var groupTemplateFields = []string{".Group", ".Users"}
+
+// referencesGroup returns true if any of the texts is a template using one of the groupTemplateFields
+func referencesGroup(texts ...string) bool {
+	for _, text := range texts {
+		if !strings.Contains(text, "{{") {
+			continue
+		}
+
+		for _, field := range groupTemplateFields {
+			if strings.Contains(text, field) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// renderTemplate expands text as a Go template. Text without any actions is returned as is.
+func renderTemplate(text string, data *gameServerTemplateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}